@@ -0,0 +1,143 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/gcloud"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"github.com/spf13/cobra"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	// cleanupOlderThan is the minimum age of the resources the cleanup command deletes.
+	cleanupOlderThan time.Duration
+
+	// cleanupDryRun makes the cleanup command list the resources it would delete without deleting them.
+	cleanupDryRun bool
+
+	cleanupCmd = &cobra.Command{
+		Use:   "cleanup",
+		Short: "Delete orphaned Cloud Run services and jobs, Cloud Functions, and container images from previous runs",
+		Long: "Find the Cloud Run services and jobs, Cloud Functions, and container images in the default project that " +
+			"were labeled by previous runs of the tool, e.g. runs that crashed before cleaning up, and delete the ones " +
+			"older than --older-than. Container images are listed in the registry set with --registry.",
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cleanupOrphans(os.Stdout, util.OSExecutor{}, time.Now().Add(-cleanupOlderThan), cleanupDryRun)
+		},
+	}
+)
+
+// cleanupOrphans lists the Cloud Run services and jobs, Cloud Functions, and container images labeled by the tool that
+// were created before the provided time, and deletes them unless dryRun is set. Every stale resource is written to the
+// provided io.Writer along with the outcome of its deletion. An error is returned if any of the deletions failed.
+func cleanupOrphans(w io.Writer, e util.Executor, before time.Time, dryRun bool) error {
+	project, err := gcloud.Project(e, "")
+	if err != nil {
+		return fmt.Errorf("[cmd.Cleanup] gcloud.Project: %w", err)
+	}
+
+	log.Println("Listing labeled Cloud Run services")
+	services, err := gcloud.ListLabeledServices(e)
+	if err != nil {
+		return fmt.Errorf("[cmd.Cleanup] gcloud.ListLabeledServices: %w", err)
+	}
+
+	log.Println("Listing labeled Cloud Run jobs")
+	jobs, err := gcloud.ListLabeledJobs(e)
+	if err != nil {
+		return fmt.Errorf("[cmd.Cleanup] gcloud.ListLabeledJobs: %w", err)
+	}
+
+	log.Println("Listing labeled Cloud Functions")
+	functions, err := gcloud.ListLabeledFunctions(e)
+	if err != nil {
+		return fmt.Errorf("[cmd.Cleanup] gcloud.ListLabeledFunctions: %w", err)
+	}
+
+	log.Println("Listing labeled container images")
+	registry, err := gcloud.ParseRegistry(registry)
+	if err != nil {
+		return fmt.Errorf("[cmd.Cleanup] gcloud.ParseRegistry: %w", err)
+	}
+
+	images, err := gcloud.ListLabeledImages(e, registry, project)
+	if err != nil {
+		return fmt.Errorf("[cmd.Cleanup] gcloud.ListLabeledImages: %w", err)
+	}
+
+	type orphan struct {
+		resource string
+		labels   gcloud.RunLabels
+		delete   func(util.Executor) error
+	}
+
+	var orphans []orphan
+	for _, s := range services {
+		orphans = append(orphans, orphan{"Cloud Run service " + s.Name, s.Labels, s.Delete})
+	}
+	for _, j := range jobs {
+		orphans = append(orphans, orphan{"Cloud Run job " + j.Name, j.Labels, j.Delete})
+	}
+	for _, f := range functions {
+		orphans = append(orphans, orphan{"Cloud Function " + f.Name, f.Labels, f.Delete})
+	}
+	for _, i := range images {
+		orphans = append(orphans, orphan{"container image " + i.Name + "@" + i.Digest, i.Labels, i.Delete})
+	}
+
+	var failed []string
+	for _, o := range orphans {
+		if !o.labels.CreatedAt.Before(before) {
+			continue
+		}
+
+		status := "would delete"
+		if !dryRun {
+			status = "deleted"
+			if err := o.delete(e); err != nil {
+				log.Printf("Failed to delete %s: %v\n", o.resource, err)
+				status = "failed to delete"
+				failed = append(failed, o.resource)
+			}
+		}
+
+		_, err := fmt.Fprintf(w, "%s\t%s (run %s, created %s)\n", status, o.resource, o.labels.RunID,
+			o.labels.CreatedAt.Format(time.RFC3339))
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(failed) > 0 {
+		return &cleanupError{cleanupErr: fmt.Errorf("failed to delete %s", strings.Join(failed, ", "))}
+	}
+
+	return nil
+}
+
+// init initializes the cleanup command's flags.
+func init() {
+	cleanupCmd.Flags().DurationVar(&cleanupOlderThan, "older-than", 6*time.Hour, "only delete resources created longer than this ago")
+	cleanupCmd.Flags().BoolVar(&cleanupDryRun, "dry-run", false, "list the resources that would be deleted without deleting them")
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCleanupOrphans(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-7 * time.Hour).Unix()
+	recent := now.Add(-time.Hour).Unix()
+
+	services := fmt.Sprintf(`[
+  {"metadata": {"name": "old-service", "labels": {"sst-run-id": "aaaa", "sst-created-at": "%d", "cloud.googleapis.com/location": "us-central1"}}},
+  {"metadata": {"name": "recent-service", "labels": {"sst-run-id": "bbbb", "sst-created-at": "%d"}}},
+  {"metadata": {"name": "unlabeled-service", "labels": {}}}
+]`, old, recent)
+	jobs := fmt.Sprintf(`[
+  {"metadata": {"name": "old-job", "labels": {"sst-run-id": "aaaa", "sst-created-at": "%d", "cloud.googleapis.com/location": "us-central1"}}},
+  {"metadata": {"name": "recent-job", "labels": {"sst-run-id": "bbbb", "sst-created-at": "%d"}}},
+  {"metadata": {"name": "unlabeled-job", "labels": {}}}
+]`, old, recent)
+	functions := fmt.Sprintf(`[
+  {"name": "projects/test-project/locations/us-central1/functions/old-function", "labels": {"sst-run-id": "aaaa", "sst-created-at": "%d"}},
+  {"name": "projects/test-project/locations/us-central1/functions/recent-function", "labels": {"sst-run-id": "bbbb", "sst-created-at": "%d"}},
+  {"name": "projects/test-project/locations/us-central1/functions/unlabeled-function"}
+]`, old, recent)
+	tags := fmt.Sprintf(`[
+  {"digest": "sha256:old", "tags": ["latest", "sst-%d-aaaa"]},
+  {"digest": "sha256:recent", "tags": ["sst-%d-bbbb"]},
+  {"digest": "sha256:unlabeled", "tags": ["v1"]}
+]`, old, recent)
+
+	for _, dryRun := range []bool{true, false} {
+		e := &util.FakeExecutor{
+			Responses: []util.FakeResponse{
+				{Match: "get-value core/project", Stdout: "test-project"},
+				{Match: "run services list", Stdout: services},
+				{Match: "run jobs list", Stdout: jobs},
+				{Match: "functions list", Stdout: functions},
+				{Match: "images list --repository=gcr.io/test-project", Stdout: "gcr.io/test-project/image"},
+				{Match: "list-tags gcr.io/test-project/image", Stdout: tags},
+			},
+		}
+
+		var b bytes.Buffer
+		if err := cleanupOrphans(&b, e, now.Add(-6*time.Hour), dryRun); err != nil {
+			t.Errorf("dry run %t: cleanupOrphans: %v", dryRun, err)
+			continue
+		}
+
+		status := "deleted"
+		if dryRun {
+			status = "would delete"
+		}
+
+		out := b.String()
+		for _, w := range []string{
+			status + "\tCloud Run service old-service (run aaaa",
+			status + "\tCloud Run job old-job (run aaaa",
+			status + "\tCloud Function old-function (run aaaa",
+			status + "\tcontainer image gcr.io/test-project/image@sha256:old (run aaaa",
+		} {
+			if !strings.Contains(out, w) {
+				t.Errorf("dry run %t: output doesn't contain %q\ngot:\n%s", dryRun, w, out)
+			}
+		}
+		if strings.Contains(out, "recent") || strings.Contains(out, "unlabeled") {
+			t.Errorf("dry run %t: output contains resources that aren't stale:\n%s", dryRun, out)
+		}
+
+		var deletes []string
+		for _, c := range e.Commands() {
+			if strings.Contains(c, "delete") {
+				deletes = append(deletes, c)
+			}
+		}
+
+		want := []string{
+			"gcloud --quiet run services delete old-service --platform=managed --region=us-central1",
+			"gcloud --quiet run jobs delete old-job --region=us-central1",
+			"gcloud --quiet functions delete old-function --region=us-central1",
+			"gcloud --quiet container images delete gcr.io/test-project/image@sha256:old --force-delete-tags",
+		}
+		if dryRun {
+			want = nil
+		}
+
+		if strings.Join(deletes, "\n") != strings.Join(want, "\n") {
+			t.Errorf("dry run %t: delete commands mismatch\nwant: %q\ngot: %q", dryRun, want, deletes)
+		}
+	}
+}
+
+func TestCleanupOrphansArtifactRegistry(t *testing.T) {
+	registry = "us-central1-docker.pkg.dev/samples"
+	defer func() { registry = "" }()
+
+	now := time.Now()
+	old := now.Add(-7 * time.Hour).Unix()
+
+	// Older gcloud versions list tags as a comma-separated string.
+	images := fmt.Sprintf(`[
+  {"package": "us-central1-docker.pkg.dev/test-project/samples/image", "version": "sha256:old", "tags": "latest,sst-%d-aaaa"},
+  {"package": "us-central1-docker.pkg.dev/test-project/samples/image", "version": "sha256:list", "tags": ["sst-%d-bbbb"]},
+  {"package": "us-central1-docker.pkg.dev/test-project/samples/image", "version": "sha256:untagged", "tags": ""}
+]`, old, old)
+
+	e := &util.FakeExecutor{
+		Responses: []util.FakeResponse{
+			{Match: "get-value core/project", Stdout: "test-project"},
+			{Match: "run services list", Stdout: "[]"},
+			{Match: "run jobs list", Stdout: "[]"},
+			{Match: "functions list", Stdout: "[]"},
+			{Match: "docker images list us-central1-docker.pkg.dev/test-project/samples", Stdout: images},
+		},
+	}
+
+	var b bytes.Buffer
+	if err := cleanupOrphans(&b, e, now.Add(-6*time.Hour), false); err != nil {
+		t.Fatalf("cleanupOrphans: %v", err)
+	}
+
+	var deletes []string
+	for _, c := range e.Commands() {
+		if strings.Contains(c, "delete") {
+			deletes = append(deletes, c)
+		}
+	}
+
+	want := []string{
+		"gcloud --quiet artifacts docker images delete us-central1-docker.pkg.dev/test-project/samples/image@sha256:old --delete-tags",
+		"gcloud --quiet artifacts docker images delete us-central1-docker.pkg.dev/test-project/samples/image@sha256:list --delete-tags",
+	}
+	if strings.Join(deletes, "\n") != strings.Join(want, "\n") {
+		t.Errorf("delete commands mismatch\nwant: %q\ngot: %q", want, deletes)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/config"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+)
+
+var (
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Work with the config files of samples",
+	}
+
+	configValidateCmd = &cobra.Command{
+		Use:   "validate [sample-dir]...",
+		Short: "Check the config files of samples for unknown keys and invalid values",
+		Long: "Strictly decode the " + config.FileName + " file of each sample and report unknown keys, duplicate keys, " +
+			"and invalid values as file:line diagnostics. Nothing is executed. Samples without a config file are valid. " +
+			"Exits with a nonzero code if any config file is invalid.",
+		Args:          cobra.MinimumNArgs(1),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var dirs []string
+			for _, a := range args {
+				d, err := sampleDirArg(a)
+				if err != nil {
+					return err
+				}
+				dirs = append(dirs, d)
+			}
+
+			return validateConfigs(os.Stdout, dirs)
+		},
+	}
+)
+
+// validateConfigs writes the problems found in the config files of the samples located in the provided directories
+// to the provided io.Writer. It returns an error if any of them are invalid.
+func validateConfigs(w io.Writer, dirs []string) error {
+	var invalid int
+	for _, d := range dirs {
+		_, err := config.Load(d)
+
+		var cErr *config.Error
+		if !errors.As(err, &cErr) {
+			if err != nil {
+				return fmt.Errorf("[cmd.Config] config.Load: %w", err)
+			}
+			continue
+		}
+
+		invalid++
+		if _, err := fmt.Fprintln(w, cErr); err != nil {
+			return fmt.Errorf("[cmd.Config] writing problems: %w", err)
+		}
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("[cmd.Config] %d invalid config file(s) found", invalid)
+	}
+
+	return nil
+}
+
+// init initializes the config command's subcommands.
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateConfigs(t *testing.T) {
+	root, err := ioutil.TempDir("", "sst-config")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	configs := map[string]string{
+		"valid":   "target: local\n",
+		"invalid": "target: local\nskip:\n  sampel: true\n",
+		"none":    "",
+	}
+
+	var dirs []string
+	for d, c := range configs {
+		dir := filepath.Join(root, d)
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("os.Mkdir: %v", err)
+		}
+		dirs = append(dirs, dir)
+
+		if c == "" {
+			continue
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(dir, "config.yaml"), []byte(c), 0644); err != nil {
+			t.Fatalf("ioutil.WriteFile: %v", err)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := validateConfigs(&b, dirs); err == nil {
+		t.Errorf("validateConfigs: got nil error with an invalid config file")
+	}
+
+	want := filepath.Join(root, "invalid", "config.yaml") + ":3: field sampel not found in type config.Skip\n"
+	if b.String() != want {
+		t.Errorf("validateConfigs: output mismatch\nwant: %q\ngot: %q", want, b.String())
+	}
+
+	b.Reset()
+	if err := validateConfigs(&b, []string{filepath.Join(root, "valid"), filepath.Join(root, "none")}); err != nil {
+		t.Errorf("validateConfigs: %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/sample"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var (
+	// discoverOutputFormat is the format the discovered samples are written to stdout in.
+	discoverOutputFormat string
+
+	// ignoreFilePath is the location of the file listing the directories skipped when discovering samples, if set.
+	ignoreFilePath string
+
+	discoverCmd = &cobra.Command{
+		Use:   "discover [repo-root]",
+		Short: "List the testable samples in a repository",
+		Long: "Walk a repository, the current directory by default, and list the directories of the testable samples " +
+			"it contains, relative to the repository root. The text output can be used as a manifest file for testing " +
+			"multiple samples.",
+		Args:          cobra.MaximumNArgs(1),
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if discoverOutputFormat != outputText && discoverOutputFormat != outputJSON {
+				return fmt.Errorf("[cmd.Discover] unsupported output format %q: must be %s or %s", discoverOutputFormat,
+					outputText, outputJSON)
+			}
+
+			root := "."
+			if len(args) > 0 {
+				root = args[0]
+			}
+
+			samples, err := discoverSamples(root, ignoreFilePath)
+			if err != nil {
+				return err
+			}
+
+			if err := writeDiscovered(os.Stdout, samples, discoverOutputFormat); err != nil {
+				return fmt.Errorf("[cmd.Discover] writing discovered samples: %w", err)
+			}
+
+			return nil
+		},
+	}
+)
+
+// discoverSamples discovers the testable samples in the repository rooted at the provided directory, skipping the
+// directories matched by the ignore file at the provided location, or by the repository's sample.IgnoreFileName file
+// if it's empty.
+func discoverSamples(root, ignoreFile string) ([]sample.DiscoveredSample, error) {
+	if ignoreFile == "" {
+		ignoreFile = filepath.Join(root, sample.IgnoreFileName)
+	}
+
+	ignore, err := sample.ReadIgnoreFile(ignoreFile)
+	if err != nil {
+		return nil, fmt.Errorf("[cmd.Discover] sample.ReadIgnoreFile: %w", err)
+	}
+
+	samples, err := sample.Discover(root, ignore)
+	if err != nil {
+		return nil, fmt.Errorf("[cmd.Discover] sample.Discover: %s: %w", root, err)
+	}
+
+	return samples, nil
+}
+
+// discoveredSampleDirs returns the absolute directories of the testable samples in the repositories rooted at the
+// provided directories. See discoverSamples.
+func discoveredSampleDirs(roots []string, ignoreFile string) ([]string, error) {
+	var dirs []string
+	for _, root := range roots {
+		samples, err := discoverSamples(root, ignoreFile)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range samples {
+			d, err := filepath.Abs(filepath.Join(root, s.Dir))
+			if err != nil {
+				return nil, err
+			}
+			dirs = append(dirs, d)
+		}
+	}
+
+	return dirs, nil
+}
+
+// writeDiscovered writes the provided discovered samples to the provided io.Writer in the provided format: one
+// directory per line for text, or a JSON array also holding the signals each sample was discovered by.
+func writeDiscovered(w io.Writer, samples []sample.DiscoveredSample, format string) error {
+	if format == outputJSON {
+		if samples == nil {
+			samples = []sample.DiscoveredSample{}
+		}
+
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(samples)
+	}
+
+	for _, s := range samples {
+		if _, err := fmt.Fprintln(w, filepath.ToSlash(s.Dir)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// init initializes the discover command's flags.
+func init() {
+	discoverCmd.Flags().StringVarP(&discoverOutputFormat, "output", "o", outputText, "format of the discovered samples written to stdout: text or json")
+	discoverCmd.Flags().StringVar(&ignoreFilePath, "ignore-file", "", "file listing the directories to skip (default "+sample.IgnoreFileName+" in the repository root)")
+}
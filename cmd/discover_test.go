@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoveredSampleDirs(t *testing.T) {
+	root, err := ioutil.TempDir("", "sst-discover")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"a/Dockerfile":  "FROM scratch\n",
+		"b/Dockerfile":  "FROM scratch\n",
+		".sstignore":    "b\n",
+		"c/README.md":   "[//]: # ({sst-run-unix})\n```\ngcloud run deploy c\n```\n",
+		"c/sub/pom.xml": "<project/>\n",
+		"d/notes.txt":   "",
+	}
+	for name, contents := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("os.MkdirAll: %v", err)
+		}
+
+		if err := ioutil.WriteFile(p, []byte(contents), 0644); err != nil {
+			t.Fatalf("ioutil.WriteFile: %v", err)
+		}
+	}
+
+	dirs, err := discoveredSampleDirs([]string{root}, "")
+	if err != nil {
+		t.Fatalf("discoveredSampleDirs: %v", err)
+	}
+
+	want := []string{filepath.Join(root, "a"), filepath.Join(root, "c")}
+	if !reflect.DeepEqual(dirs, want) {
+		t.Errorf("discoveredSampleDirs: result mismatch\nwant: %q\ngot: %q", want, dirs)
+	}
+
+	samples, err := discoverSamples(root, "")
+	if err != nil {
+		t.Fatalf("discoverSamples: %v", err)
+	}
+
+	var b bytes.Buffer
+	if err := writeDiscovered(&b, samples, outputText); err != nil {
+		t.Fatalf("writeDiscovered: %v", err)
+	}
+
+	if want := "a\nc\n"; b.String() != want {
+		t.Errorf("writeDiscovered: result mismatch\nwant: %q\ngot: %q", want, b.String())
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// The exit codes of the tool.
+const (
+	// The run failed, e.g. a build and deploy command failed or an endpoint test didn't pass.
+	exitCodeFailure = 1
+
+	// Deleting the resources created by the run failed, whether the run itself succeeded or not.
+	exitCodeCleanupFailure = 3
+
+	// The run was interrupted by SIGINT or SIGTERM, and its resources were cleaned up.
+	exitCodeInterrupted = 130
+)
+
+// cleanupError is returned when deleting any of the resources created by a run failed. It wraps the error the run
+// itself finished with, if any.
+type cleanupError struct {
+	runErr     error
+	cleanupErr error
+}
+
+func (e *cleanupError) Error() string {
+	if e.runErr == nil {
+		return fmt.Sprintf("cleaning up: %v", e.cleanupErr)
+	}
+
+	return fmt.Sprintf("%v; cleaning up: %v", e.runErr, e.cleanupErr)
+}
+
+func (e *cleanupError) Unwrap() error {
+	return e.runErr
+}
+
+// ExitCode returns the exit code the tool should exit with after Execute returned the provided error. Failing to clean
+// up takes precedence over being interrupted, which takes precedence over any other failure.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var cErr *cleanupError
+	if errors.As(err, &cErr) {
+		return exitCodeCleanupFailure
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return exitCodeInterrupted
+	}
+
+	return exitCodeFailure
+}
+
+// signalContext returns a context that's cancelled when the process receives SIGINT or SIGTERM. After the first
+// signal, the default signal behavior is restored, so a second signal terminates the process immediately.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-c:
+			log.Printf("Received %v: stopping and cleaning up created resources, send again to exit immediately\n", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+
+		signal.Stop(c)
+	}()
+
+	return ctx, cancel
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/config"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/lifecycle"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [sample-dir]",
+	Short: "Check a sample's README for problems without deploying the sample",
+	Long: "Parse the build and deploy commands in a sample's README and report problems that would make testing the " +
+		"sample fail, as file:line diagnostics. Nothing is executed. Exits with a nonzero code if any errors are found.",
+	Args:          cobra.ExactArgs(1),
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sampleDir, err := sampleDirArg(args[0])
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.Load(sampleDir)
+		if err != nil {
+			return fmt.Errorf("[cmd.Lint] config.Load: %w", err)
+		}
+
+		return lintREADME(os.Stdout, cfg.ReadmePath(sampleDir))
+	},
+}
+
+// lintREADME writes the diagnostics found in the README at the provided path to the provided io.Writer. It returns an
+// error if any of them are errors.
+func lintREADME(w io.Writer, readmePath string) error {
+	ds, err := lifecycle.Lint(readmePath)
+	if err != nil {
+		return fmt.Errorf("[cmd.Lint] lifecycle.Lint: %w", err)
+	}
+
+	var errs int
+	for _, d := range ds {
+		if _, err := fmt.Fprintln(w, d); err != nil {
+			return fmt.Errorf("[cmd.Lint] writing diagnostics: %w", err)
+		}
+
+		if d.Severity == lifecycle.SeverityError {
+			errs++
+		}
+	}
+
+	if errs > 0 {
+		return fmt.Errorf("[cmd.Lint] %d error(s) found in %s", errs, readmePath)
+	}
+
+	return nil
+}
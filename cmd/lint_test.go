@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintREADME(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sst-lint")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		readme string // README contents
+		out    string // expected string contained in the output of lintREADME
+		err    bool   // whether lintREADME is expected to return an error
+	}{
+		{
+			readme: "[//]: # ({sst-run-unix})\n" +
+				"```\n" +
+				"gcloud run deploy hello --image=gcr.io/project/hello --region=us-central1\n" +
+				"```\n",
+			out: "README.md:3: error: --region flag found",
+			err: true,
+		},
+		{
+			readme: "[//]: # ({sst-run-unix})\n" +
+				"```\n" +
+				"gcloud run deploy hello --image=gcr.io/${SST_LINT_UNDEFINED}/hello\n" +
+				"```\n",
+			out: "README.md:3: warning: environment variable $SST_LINT_UNDEFINED is not set",
+		},
+	}
+
+	for i, tc := range tests {
+		p := filepath.Join(dir, "README.md")
+		if err := ioutil.WriteFile(p, []byte(tc.readme), 0644); err != nil {
+			t.Fatalf("#%d: ioutil.WriteFile: %v", i, err)
+		}
+
+		var b bytes.Buffer
+		err := lintREADME(&b, p)
+		if (err != nil) != tc.err {
+			t.Errorf("#%d: error mismatch\nwant error: %t\ngot: %v", i, tc.err, err)
+		}
+
+		if !strings.Contains(b.String(), tc.out) {
+			t.Errorf("#%d: output doesn't contain %q\ngot:\n%s", i, tc.out, b.String())
+		}
+	}
+}
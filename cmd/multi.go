@@ -0,0 +1,455 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/sample"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	// manifestPath is the location of a file listing the directories of the samples to test, if set.
+	manifestPath string
+
+	// parallelism is the maximum number of samples tested at the same time.
+	parallelism int
+
+	// discover is whether the samples to test are discovered in the repositories rooted at the arguments.
+	discover bool
+
+	// changedSince is the git ref that only the samples affected by the files changed since are tested, if set.
+	changedSince string
+
+	// logDir is the directory the logs of each sample are written to when testing multiple samples.
+	logDir string
+)
+
+// runSample tests the sample located in the provided directory in a separate process, writing its logs to the provided
+// io.Writer, and returns the summary of the run and the exit code it finished with. It's a variable so that tests can
+// replace it.
+var runSample = runSampleProcess
+
+// sampleRunSummary is the summary of the run of one of multiple samples tested in one invocation.
+type sampleRunSummary struct {
+	*runSummary
+
+	// The exit code the run finished with, see ExitCode.
+	ExitCode int `json:"exitCode"`
+
+	// How long the run took.
+	Duration time.Duration `json:"duration"`
+
+	// The file the logs of the run were written to.
+	Log string `json:"log"`
+}
+
+// multiRunSummary is the aggregate summary of testing multiple samples in one invocation.
+type multiRunSummary struct {
+	Samples []sampleRunSummary `json:"samples"`
+	Passed  bool               `json:"passed"`
+}
+
+// selectSampleDirs returns the absolute directories of the samples to test in one invocation: the ones discovered in
+// the repositories rooted at the provided arguments with --discover, or, if no arguments or manifest file are
+// provided, in the current directory; otherwise, the ones provided as arguments or listed in the manifest file. With
+// --changed-since, only the samples affected by the files changed since the ref in the git repository of the current
+// directory are returned. External commands are executed with the provided util.Executor.
+func selectSampleDirs(e util.Executor, args []string) ([]string, error) {
+	var dirs []string
+	var err error
+	if discover || len(args) == 0 && manifestPath == "" {
+		roots := args
+		if len(roots) == 0 {
+			roots = []string{"."}
+		}
+
+		if dirs, err = discoveredSampleDirs(roots, ignoreFilePath); err != nil {
+			return nil, err
+		}
+	} else if dirs, err = sampleDirs(args, manifestPath); err != nil {
+		return nil, fmt.Errorf("[cmd.Root] finding sample directories: %w", err)
+	}
+
+	if changedSince == "" || len(dirs) == 0 {
+		return dirs, nil
+	}
+
+	changed, err := sample.ChangedFiles(e, ".", changedSince)
+	if err != nil {
+		return nil, fmt.Errorf("[cmd.Root] sample.ChangedFiles: %w", err)
+	}
+
+	var affected []string
+	for _, d := range dirs {
+		ok, err := sample.Affected(d, changed)
+		if err != nil {
+			return nil, fmt.Errorf("[cmd.Root] sample.Affected: %s: %w", d, err)
+		}
+
+		if ok {
+			affected = append(affected, d)
+		}
+	}
+
+	log.Printf("%d of %d samples affected by the %d files changed since %s\n", len(affected), len(dirs), len(changed),
+		changedSince)
+	return affected, nil
+}
+
+// sampleDirs returns the absolute directories of the samples to test: the ones provided as command line arguments, in
+// which glob patterns are expanded, followed by the ones listed in the manifest file at the provided location, if
+// any. Duplicate directories are only returned once.
+func sampleDirs(args []string, manifest string) ([]string, error) {
+	var dirs []string
+	seen := make(map[string]bool)
+	add := func(d string) {
+		if !seen[d] {
+			seen[d] = true
+			dirs = append(dirs, d)
+		}
+	}
+
+	for _, a := range args {
+		if !strings.ContainsAny(a, "*?[") {
+			d, err := sampleDirArg(a)
+			if err != nil {
+				return nil, err
+			}
+			add(d)
+			continue
+		}
+
+		matches, err := filepath.Glob(a)
+		if err != nil {
+			return nil, fmt.Errorf("filepath.Glob: %s: %w", a, err)
+		}
+
+		for _, m := range matches {
+			if fi, err := os.Stat(m); err == nil && !fi.IsDir() {
+				m = filepath.Dir(m)
+			}
+
+			d, err := filepath.Abs(m)
+			if err != nil {
+				return nil, err
+			}
+			add(d)
+		}
+	}
+
+	if manifest != "" {
+		m, err := readManifest(manifest)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, d := range m {
+			add(d)
+		}
+	}
+
+	return dirs, nil
+}
+
+// readManifest reads the manifest file at the provided location and returns the absolute directories it lists. Each
+// non-empty line that doesn't start with # is a sample directory, relative to the manifest file's directory.
+func readManifest(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open: %w", err)
+	}
+	defer f.Close()
+
+	var dirs []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(filepath.Dir(path), line)
+		}
+
+		d, err := filepath.Abs(line)
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, d)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("bufio.Scanner.Scan: %s: %w", path, err)
+	}
+
+	return dirs, nil
+}
+
+// runMultiple tests the samples located in the provided directories, up to the provided number at the same time, and
+// returns their aggregate summary. The logs of each sample are written to their own file in the provided directory. If
+// the context is done, the samples being tested are stopped and clean up their resources, and the remaining samples
+// aren't tested. The returned error makes the tool exit with the same exit code as the worst of the samples' runs.
+func runMultiple(ctx context.Context, dirs []string, parallelism int, logDir string) (*multiRunSummary, error) {
+	if parallelism < 1 {
+		return nil, fmt.Errorf("[cmd.Root] invalid parallelism %d: must be at least 1", parallelism)
+	}
+
+	summary := &multiRunSummary{Samples: make([]sampleRunSummary, len(dirs))}
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, d := range dirs {
+		s := &summary.Samples[i]
+		s.runSummary = &runSummary{Dir: d}
+		s.Log = filepath.Join(logDir, fmt.Sprintf("%03d-%s.log", i, filepath.Base(d)))
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			s.ExitCode = exitCodeInterrupted
+			s.Error = "not tested: interrupted"
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			runSampleLogged(ctx, s)
+		}()
+	}
+	wg.Wait()
+
+	summary.Passed = true
+	var failed, cleanupFailed []string
+	interrupted := false
+	for _, s := range summary.Samples {
+		switch s.ExitCode {
+		case 0:
+			continue
+		case exitCodeCleanupFailure:
+			cleanupFailed = append(cleanupFailed, s.Dir)
+		case exitCodeInterrupted:
+			interrupted = true
+		}
+
+		summary.Passed = false
+		failed = append(failed, s.Dir)
+	}
+
+	var err error
+	if len(failed) > 0 {
+		err = fmt.Errorf("%d of %d samples failed: %s", len(failed), len(dirs), strings.Join(failed, ", "))
+	}
+
+	if interrupted {
+		err = fmt.Errorf("%v: %w", err, context.Canceled)
+	}
+
+	if len(cleanupFailed) > 0 {
+		err = &cleanupError{runErr: err, cleanupErr: fmt.Errorf("samples %s", strings.Join(cleanupFailed, ", "))}
+	}
+
+	return summary, err
+}
+
+// runSampleLogged tests the sample of the provided sampleRunSummary with runSample, writing its logs to the file at
+// its Log location, and records the outcome in it.
+func runSampleLogged(ctx context.Context, s *sampleRunSummary) {
+	log.Printf("Testing sample %s, logging to %s\n", s.Dir, s.Log)
+	start := time.Now()
+	defer func() {
+		s.Duration = time.Since(start)
+		log.Printf("Finished testing sample %s with exit code %d\n", s.Dir, s.ExitCode)
+	}()
+
+	f, err := os.Create(s.Log)
+	if err != nil {
+		s.ExitCode = exitCodeFailure
+		s.Error = fmt.Sprintf("os.Create: %v", err)
+		return
+	}
+	defer f.Close()
+
+	summary, code, err := runSample(ctx, s.Dir, f)
+	if summary != nil {
+		s.runSummary = summary
+	}
+	s.ExitCode = code
+
+	if err != nil {
+		fmt.Fprintln(f, err)
+		s.Error = err.Error()
+		if s.ExitCode == 0 {
+			s.ExitCode = exitCodeFailure
+		}
+	}
+}
+
+// runSampleProcess tests the sample located in the provided directory by running the tool against it in a separate
+// process, so that the logs of every sample are kept apart. The process's stderr is written to the
+// provided io.Writer, and its JSON summary is parsed from its stdout. If the context is done, the process is sent
+// SIGTERM, so that it cleans up the resources it created before exiting. Where it can't be, e.g. on Windows, the
+// process is killed instead, and its resources are left for sst cleanup.
+func runSampleProcess(ctx context.Context, dir string, stderr io.Writer) (*runSummary, int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, exitCodeFailure, fmt.Errorf("os.Executable: %w", err)
+	}
+
+	// The trailing separator makes the directory itself, rather than its parent, the sample directory.
+	args := []string{"--output=" + outputJSON}
+	if target != "" {
+		args = append(args, "--target="+target)
+	}
+	if registry != "" {
+		args = append(args, "--registry="+registry)
+	}
+	cmd := exec.Command(exe, append(args, dir+string(filepath.Separator))...)
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = stderr
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return nil, exitCodeFailure, fmt.Errorf("exec.Cmd.Start: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+			log.Printf("Failed to interrupt sample %s, killing it: %v\n", dir, err)
+			if err := cmd.Process.Kill(); err != nil {
+				log.Printf("Failed to kill sample %s: %v\n", dir, err)
+			}
+		}
+		err = <-done
+	}
+
+	code := cmd.ProcessState.ExitCode()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, exitCodeFailure, fmt.Errorf("exec.Cmd.Wait: %w", err)
+	}
+
+	summary := &runSummary{Dir: dir}
+	if err := json.Unmarshal(stdout.Bytes(), summary); err != nil {
+		return nil, code, fmt.Errorf("json.Unmarshal: parsing sample run summary: %w", err)
+	}
+
+	return summary, code, nil
+}
+
+// writeText writes a human-readable table of the outcome of each sample's run to the provided io.Writer.
+func (r *multiRunSummary) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESULT\tSAMPLE\tDURATION\tLOG")
+	for _, s := range r.Samples {
+		result := "PASS"
+		switch s.ExitCode {
+		case 0:
+			if s.runSummary != nil && s.Skipped {
+				result = "SKIP"
+			}
+		case exitCodeCleanupFailure:
+			result = "CLEANUP FAILED"
+		case exitCodeInterrupted:
+			result = "INTERRUPTED"
+		default:
+			result = "FAIL"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%v\t%s\n", result, s.Dir, s.Duration.Round(time.Second), s.Log)
+	}
+
+	return tw.Flush()
+}
+
+// writeMultiReports writes out the reports of testing multiple samples requested through the command line flags.
+func writeMultiReports(summary *multiRunSummary) error {
+	if junitReportPath != "" {
+		var suites []util.JUnitSuite
+		for _, s := range summary.Samples {
+			suites = append(suites, s.junitSuites()...)
+		}
+
+		if err := writeJUnitReport(junitReportPath, "sst", suites); err != nil {
+			return fmt.Errorf("[cmd.Root] writing JUnit report: %w", err)
+		}
+	}
+
+	if outputFormat == outputJSON {
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		if err := e.Encode(summary); err != nil {
+			return fmt.Errorf("[cmd.Root] writing JSON summary: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := summary.writeText(os.Stdout); err != nil {
+		return fmt.Errorf("[cmd.Root] writing summary: %w", err)
+	}
+
+	return nil
+}
+
+// newLogDir returns the directory the logs of each sample are written to: the provided one, created if needed, or a new
+// temporary directory if it's empty.
+func newLogDir(dir string) (string, error) {
+	if dir == "" {
+		d, err := ioutil.TempDir("", "sst-logs")
+		if err != nil {
+			return "", fmt.Errorf("ioutil.TempDir: %w", err)
+		}
+
+		return d, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("os.MkdirAll: %w", err)
+	}
+
+	return filepath.Abs(dir)
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSampleDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sst-multi")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, d := range []string{"a", "b", "c"} {
+		if err := os.MkdirAll(filepath.Join(dir, "samples", d), 0755); err != nil {
+			t.Fatalf("os.MkdirAll: %v", err)
+		}
+	}
+
+	manifest := filepath.Join(dir, "samples.txt")
+	if err := ioutil.WriteFile(manifest, []byte("# nightly samples\nsamples/c\n\nsamples/a\n"), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile: %v", err)
+	}
+
+	got, err := sampleDirs([]string{filepath.Join(dir, "samples", "b") + "/", filepath.Join(dir, "samples", "*")}, manifest)
+	if err != nil {
+		t.Fatalf("sampleDirs: %v", err)
+	}
+
+	want := []string{
+		filepath.Join(dir, "samples", "b"),
+		filepath.Join(dir, "samples", "a"),
+		filepath.Join(dir, "samples", "c"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sampleDirs: result mismatch\nwant: %q\ngot: %q", want, got)
+	}
+}
+
+type runMultipleTest struct {
+	exitCodes []int // exit codes the runs of the samples finish with
+	exitCode  int   // expected exit code of the invocation
+	passed    bool  // expected Passed of the aggregate summary
+}
+
+var runMultipleTests = []runMultipleTest{
+	// all samples pass
+	{
+		exitCodes: []int{0, 0, 0, 0, 0},
+		exitCode:  0,
+		passed:    true,
+	},
+
+	// one sample fails
+	{
+		exitCodes: []int{0, exitCodeFailure, 0, 0, 0},
+		exitCode:  exitCodeFailure,
+	},
+
+	// cleanup failure takes precedence over other failures
+	{
+		exitCodes: []int{exitCodeFailure, 0, exitCodeCleanupFailure, 0, exitCodeInterrupted},
+		exitCode:  exitCodeCleanupFailure,
+	},
+
+	// interrupted sample
+	{
+		exitCodes: []int{0, exitCodeInterrupted, 0, 0, 0},
+		exitCode:  exitCodeInterrupted,
+	},
+}
+
+func TestRunMultiple(t *testing.T) {
+	defer func(r func(context.Context, string, io.Writer) (*runSummary, int, error)) { runSample = r }(runSample)
+
+	for i, tc := range runMultipleTests {
+		logDir, err := ioutil.TempDir("", "sst-logs")
+		if err != nil {
+			t.Fatalf("#%d: ioutil.TempDir: %v", i, err)
+		}
+
+		var dirs []string
+		codes := make(map[string]int)
+		for j, c := range tc.exitCodes {
+			d := fmt.Sprintf("/samples/sample-%d", j)
+			dirs = append(dirs, d)
+			codes[d] = c
+		}
+
+		var mu sync.Mutex
+		running, maxRunning := 0, 0
+		runSample = func(ctx context.Context, dir string, stderr io.Writer) (*runSummary, int, error) {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+
+			fmt.Fprintf(stderr, "testing %s\n", dir)
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+
+			return &runSummary{Sample: filepath.Base(dir), Dir: dir, Passed: codes[dir] == 0}, codes[dir], nil
+		}
+
+		summary, err := runMultiple(context.Background(), dirs, 2, logDir)
+		if code := ExitCode(err); code != tc.exitCode {
+			t.Errorf("#%d: exit code mismatch\nwant: %d\ngot: %d (%v)", i, tc.exitCode, code, err)
+		}
+
+		if summary.Passed != tc.passed {
+			t.Errorf("#%d: passed mismatch\nwant: %t\ngot: %t", i, tc.passed, summary.Passed)
+		}
+
+		if maxRunning > 2 {
+			t.Errorf("#%d: %d samples tested at the same time, want at most 2", i, maxRunning)
+		}
+
+		for j, s := range summary.Samples {
+			if s.Dir != dirs[j] || s.ExitCode != tc.exitCodes[j] {
+				t.Errorf("#%d: sample %d mismatch\nwant: %s exit code %d\ngot: %s exit code %d", i, j, dirs[j],
+					tc.exitCodes[j], s.Dir, s.ExitCode)
+			}
+
+			b, err := ioutil.ReadFile(s.Log)
+			if err != nil || !strings.Contains(string(b), "testing "+dirs[j]) {
+				t.Errorf("#%d: sample %d log %s doesn't contain the sample's logs: %q %v", i, j, s.Log, b, err)
+			}
+		}
+
+		os.RemoveAll(logDir)
+	}
+}
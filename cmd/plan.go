@@ -0,0 +1,130 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/sample"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan [sample-dir]",
+	Short: "Print the commands that would be executed to test a sample without executing them",
+	Long: "Print the build and deploy commands that would be executed to test a sample, exactly as they would be " +
+		"executed and along with where each came from, followed by the cleanup commands. Only the read-only commands " +
+		"needed to resolve the sample's container image URL are executed.",
+	Args:          cobra.ExactArgs(1),
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sampleDir, err := sampleDirArg(args[0])
+		if err != nil {
+			return err
+		}
+
+		s, err := newSample(sampleDir, util.OSExecutor{})
+		if err != nil {
+			return err
+		}
+
+		if err := writePlan(os.Stdout, s); err != nil {
+			return fmt.Errorf("[cmd.Plan] writing plan: %w", err)
+		}
+
+		return nil
+	},
+}
+
+// writePlan writes the commands that would be executed to test the provided sample to the provided io.Writer.
+func writePlan(w io.Writer, s *sample.Sample) error {
+	image := s.CloudContainerImageURL()
+	if image == "" {
+		image = "none"
+	}
+
+	_, err := fmt.Fprintf(w, "Sample: %s\nService: %s\nContainer image: %s\n\nBuild and deploy commands:\n",
+		s.Name, s.Service.Describe(), image)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range s.BuildDeployLifecycle {
+		if _, err := fmt.Fprintf(w, "  [%s] %s\n", c.Source, util.CommandLine(c.Cmd)); err != nil {
+			return err
+		}
+
+		// Show the command as written in the README, before any expansions or replacements.
+		if c.Source.Text == "" {
+			continue
+		}
+
+		for _, l := range strings.Split(c.Source.Text, "\n") {
+			if _, err := fmt.Fprintf(w, "      # %s\n", l); err != nil {
+				return err
+			}
+		}
+	}
+
+	var labelCmds []*exec.Cmd
+	if !s.LabeledOnDeploy() {
+		labelCmds = append(labelCmds, s.Service.LabelCmd(s.Labels))
+	}
+	cleanupCmds := []*exec.Cmd{s.Service.DeleteCmd()}
+	if s.CloudContainerImageURL() != "" {
+		labelCmds = append(labelCmds, s.TagCloudContainerImageCmd())
+
+		// A local container image can only be deleted once the container running it is.
+		if s.Local {
+			cleanupCmds = append(cleanupCmds, s.DeleteCloudContainerImageCmd())
+		} else {
+			cleanupCmds = append([]*exec.Cmd{s.DeleteCloudContainerImageCmd()}, cleanupCmds...)
+		}
+	}
+
+	for _, c := range s.CleanupLifecycle {
+		cleanupCmds = append(cleanupCmds, c.Cmd)
+	}
+
+	if err := writeCommands(w, "Labeling commands:", labelCmds); err != nil {
+		return err
+	}
+
+	return writeCommands(w, "Cleanup commands:", cleanupCmds)
+}
+
+// writeCommands writes a titled list of commands to the provided io.Writer. nil commands are skipped.
+func writeCommands(w io.Writer, title string, cmds []*exec.Cmd) error {
+	if _, err := fmt.Fprintf(w, "\n%s\n", title); err != nil {
+		return err
+	}
+
+	for _, c := range cmds {
+		if c == nil {
+			continue
+		}
+
+		if _, err := fmt.Fprintf(w, "  %s\n", util.CommandLine(c)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWritePlan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sst-plan")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	readme := "Build the sample:\n" +
+		"[//]: # ({sst-run-unix})\n" +
+		"```\n" +
+		"gcloud builds submit --tag=gcr.io/project/hello\n" +
+		"\n" +
+		"gcloud run deploy hello \\\n" +
+		"--image=gcr.io/project/hello\n" +
+		"```\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte(readme), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile: %v", err)
+	}
+
+	e := &util.FakeExecutor{
+		Responses: []util.FakeResponse{
+			{Match: "rev-parse", Stdout: "abc1234"},
+			{Match: "get-value core/project", Stdout: "test-project"},
+		},
+	}
+
+	s, err := newSample(dir, e)
+	if err != nil {
+		t.Fatalf("newSample: %v", err)
+	}
+
+	var b bytes.Buffer
+	if err := writePlan(&b, s); err != nil {
+		t.Fatalf("writePlan: %v", err)
+	}
+	out := b.String()
+
+	image := s.CloudContainerImageURL()
+	want := []string{
+		"  [README.md:4] gcloud --quiet builds submit --tag=" + image + "\n",
+		"      # gcloud builds submit --tag=gcr.io/project/hello\n",
+		"  [README.md:6] gcloud --quiet run deploy " + s.ServiceName + " --image=" + image + " --update-labels=" +
+			s.Labels.ServiceLabels() + "\n",
+		"      # gcloud run deploy hello \\\n      # --image=gcr.io/project/hello\n",
+		"  gcloud --quiet container images add-tag " + image + " " + image + ":" + s.Labels.ImageTag() + "\n",
+		"  gcloud --quiet container images delete " + image + " --force-delete-tags\n",
+		"  gcloud --quiet run services delete " + s.ServiceName + " --platform=managed\n",
+	}
+	for _, w := range want {
+		if !strings.Contains(out, w) {
+			t.Errorf("plan doesn't contain %q\ngot:\n%s", w, out)
+		}
+	}
+
+	// The service is labeled by its deploy command, so it doesn't need to be labeled once it's deployed.
+	if strings.Contains(out, "run services update") {
+		t.Errorf("plan contains a labeling command for the service\ngot:\n%s", out)
+	}
+
+	// Only the commands resolving the container image URL should have been executed.
+	if c := e.Commands(); len(c) != 2 {
+		t.Errorf("got %d executed commands, want 2: %q", len(c), c)
+	}
+}
@@ -15,79 +15,218 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"github.com/GoogleCloudPlatform/serverless-sample-tester/internal/sample"
-	"github.com/GoogleCloudPlatform/serverless-sample-tester/internal/util"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/config"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/gcloud"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/sample"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+	"io"
 	"log"
-	"os/exec"
+	"os"
 	"path/filepath"
+	"runtime/debug"
+	"strings"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
 )
 
 var (
+	// junitReportPath is the location the JUnit XML report will be written to, if set.
+	junitReportPath string
+
+	// outputFormat is the format the results of the run are written to stdout in.
+	outputFormat string
+
+	// target and registry override the target and registry config keys of the tested samples, if set.
+	target   string
+	registry string
+
 	rootCmd = &cobra.Command{
-		Use:   "sst [sample-dir]",
+		Use:   "sst [sample-dir]...",
 		Short: "An end-to-end tester for GCP samples",
-		Args:  cobra.ExactArgs(1),
+		Long: "An end-to-end tester for GCP samples. Multiple samples can be tested in one invocation by passing " +
+			"several sample directories, glob patterns matching sample directories, or a manifest file listing them, " +
+			"or by discovering the samples in repositories with --discover. With --changed-since, only the samples " +
+			"affected by the changes made since a git ref are tested.",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && manifestPath == "" && !discover && changedSince == "" {
+				return errors.New("requires at least 1 sample directory, or a manifest file")
+			}
+			return nil
+		},
 		SilenceErrors: true,
-		SilenceUsage: true,
+		SilenceUsage:  true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Parse sample directory from command line argument
-			sampleDir, err := filepath.Abs(filepath.Dir(args[0]))
-			if err != nil {
-				return err
+			if outputFormat != outputText && outputFormat != outputJSON {
+				return fmt.Errorf("[cmd.Root] unsupported output format %q: must be %s or %s", outputFormat, outputText, outputJSON)
 			}
 
-			log.Println("Setting up configuration values")
-			// Set up config file location
-			viper.SetConfigName("config")
-			viper.SetConfigType("yaml")
-			viper.AddConfigPath(sampleDir)
-			s, err := sample.NewSample(sampleDir)
-			if err != nil {
-				return err
-			}
+			ctx, cancel := signalContext()
+			defer cancel()
 
-			log.Println("Loading test endpoints")
-			swagger := util.LoadTestEndpoints()
+			if len(args) != 1 || manifestPath != "" || discover || changedSince != "" || strings.ContainsAny(args[0], "*?[") {
+				dirs, err := selectSampleDirs(util.OSExecutor{}, args)
+				if err != nil {
+					return err
+				}
 
-			log.Println("Building and deploying sample to Cloud Run")
-			err = s.BuildDeployLifecycle.Execute(s.Dir)
-			defer s.Service.Delete(s.Dir)
-			defer s.DeleteCloudContainerImage()
-			if err != nil {
-				return fmt.Errorf("[cmd.Root] building and deploying sample to Cloud Run: %w", err)
-			}
+				if changedSince != "" && len(dirs) == 0 {
+					log.Printf("No samples affected by the changes made since %s\n", changedSince)
+					return nil
+				}
 
-			log.Println("Getting identity token for gcloud auhtorized account")
-			var identToken string
-			a := append(util.GcloudCommonFlags, "auth", "print-identity-token")
-			identToken, err = util.ExecCommand(exec.Command("gcloud", a...), s.Dir)
-			if err != nil {
-				return fmt.Errorf("[cmd.Root] getting identity token for gcloud auhtorized account: %w", err)
+				return runAll(ctx, dirs)
 			}
 
-			log.Println("Checking endpoints for expected results")
-			serviceURL, err := s.Service.URL(s.Dir)
+			sampleDir, err := sampleDirArg(args[0])
 			if err != nil {
-				return fmt.Errorf("[cmd.Root] getting Cloud Run service URL: %w", err)
+				return err
 			}
 
-			log.Println("Validating Cloud Run service endpoints for expected status codes")
-			allTestsPassed, err := util.ValidateEndpoints(serviceURL, &swagger.Paths, identToken)
-			if err != nil {
-				return fmt.Errorf("[cmd.Root] validating Cloud Run service endpoints for expected status codes: %w", err)
+			summary := &runSummary{Dir: sampleDir}
+			err = run(ctx, sampleDir, util.OSExecutor{}, summary)
+			if rErr := writeReports(summary, err); rErr != nil && err == nil {
+				err = rErr
 			}
 
-			if !allTestsPassed {
-				return fmt.Errorf("all tests did not pass")
-			}
-			return nil
+			return err
 		},
 	}
 )
 
+// runAll tests the samples located in the provided directories with runMultiple and writes out the aggregate reports.
+func runAll(ctx context.Context, dirs []string) error {
+	if len(dirs) == 0 {
+		return errors.New("[cmd.Root] no sample directories found")
+	}
+
+	d, err := newLogDir(logDir)
+	if err != nil {
+		return fmt.Errorf("[cmd.Root] creating log directory: %w", err)
+	}
+
+	log.Printf("Testing %d samples, %d at a time, logging to %s\n", len(dirs), parallelism, d)
+	summary, err := runMultiple(ctx, dirs, parallelism, d)
+	if summary == nil {
+		return err
+	}
+
+	if rErr := writeMultiReports(summary); rErr != nil && err == nil {
+		err = rErr
+	}
+
+	return err
+}
+
+// run builds and deploys the sample located in the provided directory, validates its endpoints, and cleans up the
+// resources it created. External commands are executed with the provided util.Executor. If the context is done, the
+// in-flight build and deploy command is killed and the run stops. The resources are cleaned up no matter how the run
+// ends, including on panics. The outcome of every step is recorded in the provided runSummary.
+func run(ctx context.Context, sampleDir string, e util.Executor, summary *runSummary) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pErr := fmt.Errorf("[cmd.Root] panic: %v\n%s", r, debug.Stack())
+
+			var cErr *cleanupError
+			if errors.As(err, &cErr) {
+				cErr.runErr = pErr
+			} else {
+				err = pErr
+			}
+		}
+	}()
+
+	s, err := newSample(sampleDir, e)
+	if errors.Is(err, sample.ErrSkipped) {
+		log.Printf("Skipping sample: %v\n", err)
+		summary.Skipped = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	summary.Sample = s.Name
+	summary.Service = s.ServiceName
+	summary.Image = s.CloudContainerImageURL()
+
+	defer func() {
+		if cErr := cleanup(s, summary); cErr != nil {
+			err = &cleanupError{runErr: err, cleanupErr: cErr}
+		}
+	}()
+
+	summary.Lifecycle, err = s.Deploy(ctx)
+	if err != nil {
+		return fmt.Errorf("[cmd.Root] %w", err)
+	}
+
+	v, err := s.Validate(ctx)
+	summary.ServiceURL = v.ServiceURL
+	summary.Tests = v.Tests
+	summary.Execution = v.Execution
+
+	if outputFormat == outputText {
+		if wErr := writeValidation(os.Stdout, v, err); wErr != nil {
+			return fmt.Errorf("[cmd.Root] writing test report summary: %w", wErr)
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("[cmd.Root] %w", err)
+	}
+
+	return nil
+}
+
+// writeValidation writes a human-readable summary of the provided sample.Validation to the provided io.Writer, unless
+// the provided error returned along with it means the tests didn't finish.
+func writeValidation(w io.Writer, v *sample.Validation, err error) error {
+	if v.Execution != nil {
+		_, err := fmt.Fprintf(w, "Execution %s: %d tasks succeeded, %d tasks failed\n", v.Execution.Name,
+			v.Execution.SucceededCount, v.Execution.FailedCount)
+		return err
+	}
+
+	if v.Tests == nil || (err != nil && !errors.Is(err, sample.ErrTestsFailed)) {
+		return nil
+	}
+
+	return v.Tests.WriteSummary(w)
+}
+
+// cleanup deletes the resources created by a run of the provided sample and records the outcome of every deletion in
+// the provided runSummary. An error is returned if any of the deletions failed.
+func cleanup(s *sample.Sample, summary *runSummary) error {
+	results, err := s.Cleanup()
+	for _, r := range results {
+		summary.addCleanup(r.Resource, r.Err)
+	}
+
+	return err
+}
+
+// sampleDirArg parses the sample directory from a command line argument.
+func sampleDirArg(arg string) (string, error) {
+	return filepath.Abs(filepath.Dir(arg))
+}
+
+// newSample creates a sample.Sample for the sample located in the provided directory, with its config file
+// overridden by the --target and --registry flags.
+func newSample(sampleDir string, e util.Executor) (*sample.Sample, error) {
+	s, err := sample.NewSample(sampleDir, e, config.FileLoader{}, sample.Options{Target: target, Registry: registry})
+	if err != nil {
+		return nil, fmt.Errorf("[cmd.Root] sample.NewSample: %w", err)
+	}
+
+	return s, nil
+}
+
 // Execute executes the root command.
 func Execute() error {
 	return rootCmd.Execute()
@@ -95,5 +234,21 @@ func Execute() error {
 
 // init initializes the tool.
 func init() {
-	// Initialization goes here
+	rootCmd.AddCommand(planCmd)
+	rootCmd.AddCommand(cleanupCmd)
+	rootCmd.AddCommand(lintCmd)
+	rootCmd.AddCommand(discoverCmd)
+	rootCmd.AddCommand(configCmd)
+
+	rootCmd.Flags().StringVarP(&outputFormat, "output", "o", outputText, "format of the results written to stdout: text or json")
+	rootCmd.Flags().StringVar(&junitReportPath, "report-junit", "", "write a JUnit XML report of the lifecycle steps and endpoint tests to this file")
+	rootCmd.Flags().StringVar(&manifestPath, "manifest", "", "test the samples listed in this file, one directory per line, relative to the file's directory")
+	rootCmd.Flags().IntVar(&parallelism, "parallelism", 1, "maximum number of samples tested at the same time when testing multiple samples")
+	rootCmd.Flags().BoolVar(&discover, "discover", false, "test all the samples discovered in the repositories rooted at the arguments, the current directory by default (see sst discover)")
+	rootCmd.Flags().StringVar(&ignoreFilePath, "ignore-file", "", "with --discover, file listing the directories to skip (default "+sample.IgnoreFileName+" in the repository root)")
+	rootCmd.Flags().StringVar(&changedSince, "changed-since", "", "only test the samples affected by the files changed since this git ref, e.g. origin/main; without sample directories, the samples discovered in the current directory are considered")
+	rootCmd.Flags().StringVar(&logDir, "log-dir", "", "directory the logs of each sample are written to when testing multiple samples (default a new temporary directory)")
+
+	rootCmd.PersistentFlags().StringVar(&target, "target", "", "where to test the sample: cloud to deploy it to GCP, or local to build and run it with Docker without GCP; overrides the target config key (default "+config.TargetCloud+")")
+	rootCmd.PersistentFlags().StringVar(&registry, "registry", "", "container registry to push images to: a Container Registry host (e.g. gcr.io) or an Artifact Registry repository (e.g. us-central1-docker.pkg.dev/REPOSITORY); overrides the registry config key (default "+gcloud.DefaultRegistry+")")
 }
@@ -0,0 +1,324 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type runTest struct {
+	readme    string   // README.md contents of the sample, if any
+	config    string   // config.yaml contents of the sample, if any
+	status    int      // status code returned by the fake Cloud Run service
+	deployErr bool     // whether the fake deploy command fails
+	deleteErr bool     // whether the fake service delete command fails
+	notFound  bool     // whether the fake delete commands fail because there's nothing to delete
+	cancel    bool     // whether the run's context is cancelled before it starts
+	noImage   bool     // whether the sample doesn't build a container image, e.g. because it's a Cloud Function
+	cleanups  int      // number of additional cleanup commands set in the config file
+	skipped   bool     // whether the sample is expected to be skipped
+	exitCode  int      // expected exit code of the run
+	commands  []string // substrings of the commands expected to be executed, in order
+}
+
+var runTests = []runTest{
+	// default lifecycle, endpoint passes
+	{
+		status:   http.StatusOK,
+		exitCode: 0,
+		commands: []string{
+			"gcloud --quiet builds submit --tag=gcr.io/test-project/",
+			"gcloud --quiet run deploy ",
+			"gcloud --quiet container images add-tag ",
+			"gcloud --quiet auth print-identity-token",
+			"gcloud --quiet run --platform=managed services describe ",
+			"gcloud --quiet container images delete gcr.io/test-project/",
+			"gcloud --quiet run services delete ",
+		},
+	},
+
+	// README lifecycle, endpoint fails
+	{
+		readme: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"gcloud run deploy hello --image=gcr.io/project/hello\n" +
+			"```\n",
+		status:   http.StatusInternalServerError,
+		exitCode: exitCodeFailure,
+		commands: []string{
+			"gcloud --quiet run deploy ",
+			"gcloud --quiet container images add-tag ",
+			"gcloud --quiet auth print-identity-token",
+			"gcloud --quiet run --platform=managed services describe ",
+			"gcloud --quiet container images delete ",
+			"gcloud --quiet run services delete ",
+		},
+	},
+
+	// deploy fails, resources are still cleaned up
+	{
+		deployErr: true,
+		exitCode:  exitCodeFailure,
+		commands: []string{
+			"gcloud --quiet builds submit ",
+			"gcloud --quiet run deploy ",
+			"gcloud --quiet container images delete ",
+			"gcloud --quiet run services delete ",
+		},
+	},
+
+	// endpoint passes, but deleting the service fails
+	{
+		status:    http.StatusOK,
+		deleteErr: true,
+		exitCode:  exitCodeCleanupFailure,
+		commands: []string{
+			"gcloud --quiet builds submit ",
+			"gcloud --quiet run deploy ",
+			"gcloud --quiet container images add-tag ",
+			"gcloud --quiet auth print-identity-token",
+			"gcloud --quiet run --platform=managed services describe ",
+			"gcloud --quiet container images delete ",
+			"gcloud --quiet run services delete ",
+		},
+	},
+
+	// README lifecycle deploys a Cloud Function, no container image is tagged or deleted
+	{
+		readme: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"gcloud functions deploy hello_world --runtime=go113 --trigger-http\n" +
+			"```\n",
+		status:   http.StatusOK,
+		noImage:  true,
+		exitCode: 0,
+		commands: []string{
+			"gcloud --quiet functions deploy ",
+			"gcloud --quiet auth print-identity-token",
+			"gcloud --quiet functions describe ",
+			"gcloud --quiet functions delete ",
+		},
+	},
+
+	// README lifecycle deploys to App Engine, the non-promoted version is tested and deleted
+	{
+		readme: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"gcloud app deploy app.yaml\n" +
+			"```\n",
+		status:   http.StatusOK,
+		noImage:  true,
+		exitCode: 0,
+		commands: []string{
+			"gcloud --quiet app deploy --no-promote --version=",
+			"gcloud --quiet auth print-identity-token",
+			"gcloud --quiet app versions list --filter=version.id=",
+			"gcloud --quiet app versions delete ",
+		},
+	},
+
+	// README lifecycle deploys a Cloud Run job, expected tasks succeed and lines are logged
+	{
+		readme: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"gcloud run jobs deploy hello --image=gcr.io/project/hello --tasks=2\n" +
+			"```\n",
+		config:   "job:\n  tasks: 2\n  logs:\n  - hello from task\n",
+		exitCode: 0,
+		commands: []string{
+			"gcloud --quiet run jobs deploy ",
+			"gcloud --quiet container images add-tag ",
+			"gcloud --quiet run jobs execute ",
+			"gcloud --quiet logging read ",
+			"gcloud --quiet container images delete ",
+			"gcloud --quiet run jobs delete ",
+		},
+	},
+
+	// README lifecycle deploys a Cloud Run job, fewer tasks succeed than expected
+	{
+		readme: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"gcloud run jobs deploy hello --image=gcr.io/project/hello --tasks=3\n" +
+			"```\n",
+		config:   "job:\n  tasks: 3\n",
+		exitCode: exitCodeFailure,
+		commands: []string{
+			"gcloud --quiet run jobs deploy ",
+			"gcloud --quiet container images add-tag ",
+			"gcloud --quiet run jobs execute ",
+			"gcloud --quiet container images delete ",
+			"gcloud --quiet run jobs delete ",
+		},
+	},
+
+	// config file sets the region, skips the endpoint tests, and adds a cleanup command
+	{
+		config: "region: us-east1\n" +
+			"skip:\n  endpoints: true\n" +
+			"cleanup:\n- gcloud sql instances delete hello-db\n",
+		status:   http.StatusInternalServerError,
+		cleanups: 1,
+		exitCode: 0,
+		commands: []string{
+			"gcloud --quiet builds submit --tag=gcr.io/test-project/",
+			"gcloud --quiet run deploy ",
+			"gcloud --quiet container images add-tag ",
+			"gcloud --quiet container images delete gcr.io/test-project/",
+			"--platform=managed --region=us-east1",
+			"gcloud --quiet sql instances delete hello-db",
+		},
+	},
+
+	// config file skips the sample
+	{
+		config:  "skip:\n  sample: true\n",
+		skipped: true,
+	},
+
+	// config file has an unknown key
+	{
+		config:   "skip:\n  sampel: true\n",
+		exitCode: exitCodeFailure,
+	},
+
+	// deploy fails before creating any resources, nothing to delete
+	{
+		deployErr: true,
+		notFound:  true,
+		exitCode:  exitCodeFailure,
+		commands: []string{
+			"gcloud --quiet builds submit --tag=gcr.io/test-project/",
+			"gcloud --quiet run deploy ",
+			"gcloud --quiet container images delete gcr.io/test-project/",
+			"gcloud --quiet run services delete ",
+		},
+	},
+
+	// interrupted before the lifecycle starts, resources are still cleaned up
+	{
+		cancel:   true,
+		exitCode: exitCodeInterrupted,
+		commands: []string{
+			"gcloud --quiet container images delete ",
+			"gcloud --quiet run services delete ",
+		},
+	},
+}
+
+func TestRun(t *testing.T) {
+
+	for i, tc := range runTests {
+		dir, err := ioutil.TempDir("", "sst-run")
+		if err != nil {
+			t.Fatalf("#%d: ioutil.TempDir: %v", i, err)
+		}
+
+		if tc.readme != "" {
+			if err := ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte(tc.readme), 0644); err != nil {
+				t.Fatalf("#%d: ioutil.WriteFile: %v", i, err)
+			}
+		}
+
+		if tc.config != "" {
+			if err := ioutil.WriteFile(filepath.Join(dir, "config.yaml"), []byte(tc.config), 0644); err != nil {
+				t.Fatalf("#%d: ioutil.WriteFile: %v", i, err)
+			}
+		}
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer test-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(tc.status)
+			fmt.Fprint(w, "hello world")
+		}))
+
+		deployExitCode := 0
+		if tc.deployErr {
+			deployExitCode = 1
+		}
+
+		deleteExitCode := 0
+		if tc.deleteErr {
+			deleteExitCode = 1
+		}
+
+		e := &util.FakeExecutor{
+			Responses: []util.FakeResponse{
+				{Match: "rev-parse", Stdout: "abc1234"},
+				{Match: "get-value core/project", Stdout: "test-project"},
+				{Match: "run deploy", ExitCode: deployExitCode},
+				{Match: "print-identity-token", Stdout: "test-token"},
+				{Match: "services describe", Stdout: ts.URL},
+				{Match: "functions describe", Stdout: ts.URL},
+				{Match: "app versions list", Stdout: ts.URL},
+				{Match: "jobs execute", Stdout: `{"metadata": {"name": "hello-abc12"}, "status": {"succeededCount": 2}}`},
+				{Match: "logging read", Stdout: "starting\nhello from task 0\nhello from task 1"},
+				{Match: "services delete", ExitCode: deleteExitCode},
+			},
+		}
+
+		if tc.notFound {
+			e.Responses = append([]util.FakeResponse{
+				{Match: " delete ", Stdout: "ERROR: NOT_FOUND: Resource not found", ExitCode: 1},
+			}, e.Responses...)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		if tc.cancel {
+			cancel()
+		}
+
+		summary := &runSummary{Dir: dir}
+		err = run(ctx, dir, e, summary)
+		cancel()
+		ts.Close()
+		os.RemoveAll(dir)
+
+		if code := ExitCode(err); code != tc.exitCode {
+			t.Errorf("#%d: exit code mismatch\nwant: %d\ngot: %d (%v)", i, tc.exitCode, code, err)
+		}
+
+		if summary.Skipped != tc.skipped {
+			t.Errorf("#%d: skipped mismatch\nwant: %t\ngot: %t", i, tc.skipped, summary.Skipped)
+		}
+
+		// Nothing is executed if the sample isn't created.
+		if tc.skipped || (tc.exitCode != 0 && len(tc.commands) == 0) {
+			if cmds := e.Commands(); len(cmds) != 0 {
+				t.Errorf("#%d: got commands %q, want none", i, cmds)
+			}
+			continue
+		}
+
+		// The first two commands get the container image tag and the project.
+		cmds := e.Commands()[2:]
+		if len(cmds) != len(tc.commands) {
+			t.Errorf("#%d: commands mismatch\nwant: %q\ngot: %q", i, tc.commands, cmds)
+			continue
+		}
+
+		for j, c := range tc.commands {
+			if !strings.Contains(cmds[j], c) {
+				t.Errorf("#%d: command %d mismatch\nwant: %s\ngot: %s", i, j, c, cmds[j])
+			}
+		}
+
+		wantCleanups := 2 + tc.cleanups
+		if tc.noImage {
+			wantCleanups--
+		}
+		if len(summary.Cleanup) != wantCleanups {
+			t.Errorf("#%d: got %d cleanup results, want %d", i, len(summary.Cleanup), wantCleanups)
+		}
+	}
+}
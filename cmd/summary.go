@@ -0,0 +1,134 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/gcloud"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"io"
+	"os"
+)
+
+// runSummary is a machine-readable summary of a single run of the tool against a sample.
+type runSummary struct {
+	// The name and local directory of the sample.
+	Sample string `json:"sample"`
+	Dir    string `json:"dir"`
+
+	// The generated service name and container image URL the sample was deployed with.
+	Service string `json:"service"`
+	Image   string `json:"image"`
+
+	// The results of the build and deploy lifecycle commands that were executed.
+	Lifecycle []util.CommandResult `json:"lifecycle"`
+
+	// The root URL of the deployed Cloud Run service.
+	ServiceURL string `json:"serviceURL,omitempty"`
+
+	// The outcome of executing the deployed Cloud Run job, if the sample was deployed as one.
+	Execution *gcloud.JobExecution `json:"execution,omitempty"`
+
+	// The results of the endpoint tests.
+	Tests *util.TestReport `json:"tests,omitempty"`
+
+	// The outcomes of deleting the resources created by the run.
+	Cleanup []cleanupResult `json:"cleanup"`
+
+	// Whether the sample was skipped because its config file says so.
+	Skipped bool `json:"skipped,omitempty"`
+
+	// Whether the run succeeded, and if not, why.
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// cleanupResult is the outcome of deleting a single resource created by a run.
+type cleanupResult struct {
+	Resource string `json:"resource"`
+	Error    string `json:"error,omitempty"`
+}
+
+// addCleanup records the outcome of deleting the provided resource.
+func (r *runSummary) addCleanup(resource string, err error) {
+	c := cleanupResult{Resource: resource}
+	if err != nil {
+		c.Error = err.Error()
+	}
+
+	r.Cleanup = append(r.Cleanup, c)
+}
+
+// junitSuites returns the JUnit testsuites describing the run. Besides the lifecycle commands and endpoint tests, the
+// outcome of the whole run is reported as the sst/run testcase, so that failures that happen outside of them, e.g.
+// an invalid config file, show up in the report, along with the outcome of every resource deletion.
+func (r *runSummary) junitSuites() []util.JUnitSuite {
+	run := util.JUnitSuite{Name: r.Sample + " run", Cases: []util.JUnitCase{{Name: "sst/run", Failure: r.Error}}}
+	for _, c := range r.Cleanup {
+		run.Cases = append(run.Cases, util.JUnitCase{Name: "sst/cleanup " + c.Resource, Failure: c.Error})
+	}
+
+	return []util.JUnitSuite{
+		{Name: r.Sample + " build and deploy", Commands: r.Lifecycle},
+		{Name: r.Sample + " endpoints", Tests: r.Tests},
+		run,
+	}
+}
+
+// writeJSON writes the summary to the provided io.Writer as a single JSON document.
+func (r *runSummary) writeJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(r)
+}
+
+// writeReports finalizes the summary with the error the run finished with, if any, and writes out the reports
+// requested through the command line flags.
+func writeReports(summary *runSummary, runErr error) error {
+	summary.Passed = runErr == nil
+	if runErr != nil {
+		summary.Error = runErr.Error()
+	}
+
+	if junitReportPath != "" {
+		if err := writeJUnitReport(junitReportPath, summary.Sample, summary.junitSuites()); err != nil {
+			return fmt.Errorf("[cmd.Root] writing JUnit report: %w", err)
+		}
+	}
+
+	if outputFormat == outputJSON {
+		if err := summary.writeJSON(os.Stdout); err != nil {
+			return fmt.Errorf("[cmd.Root] writing JSON summary: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// writeJUnitReport writes the provided suites as a JUnit XML report to the file at the provided location.
+func writeJUnitReport(path, className string, suites []util.JUnitSuite) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("os.Create: %w", err)
+	}
+
+	if err := util.WriteJUnitReport(f, className, suites); err != nil {
+		f.Close()
+		return fmt.Errorf("util.WriteJUnitReport: %w", err)
+	}
+
+	return f.Close()
+}
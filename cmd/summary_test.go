@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"strings"
+	"testing"
+)
+
+func TestRunSummaryJUnitSuites(t *testing.T) {
+	summary := &runSummary{Sample: "hello"}
+	summary.addCleanup("Cloud Run service hello-1234", errors.New("permission denied"))
+	summary.addCleanup("container image gcr.io/project/hello", nil)
+	summary.Error = "[cmd.Root] sample.NewSample: config.yaml: unknown key sampel"
+
+	var b bytes.Buffer
+	if err := util.WriteJUnitReport(&b, "hello", summary.junitSuites()); err != nil {
+		t.Fatalf("util.WriteJUnitReport: %v", err)
+	}
+	out := b.String()
+
+	want := []string{
+		`<testsuite name="hello run" tests="3" failures="2" time="0.000">`,
+		`<testcase name="sst/run" classname="hello" time="0.000">`,
+		`<failure message="[cmd.Root] sample.NewSample: config.yaml: unknown key sampel">`,
+		`<testcase name="sst/cleanup Cloud Run service hello-1234" classname="hello" time="0.000">`,
+		`<failure message="permission denied">`,
+	}
+	for _, w := range want {
+		if !strings.Contains(out, w) {
+			t.Errorf("report doesn't contain %s\ngot:\n%s", w, out)
+		}
+	}
+}
@@ -5,5 +5,5 @@ go 1.14
 require (
 	github.com/getkin/kin-openapi v0.18.0
 	github.com/spf13/cobra v1.0.0
-	github.com/spf13/viper v1.7.1
+	gopkg.in/yaml.v2 v2.3.0
 )
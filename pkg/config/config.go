@@ -0,0 +1,362 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package config reads and validates the config files of samples.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/gcloud"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileName is the name of the config file in a sample's directory. A config file named AltFileName is read too, but
+// a sample can't have both.
+const (
+	FileName    = "config.yaml"
+	AltFileName = "config.yml"
+)
+
+// The targets a sample can be tested on.
+const (
+	TargetCloud = "cloud"
+	TargetLocal = "local"
+)
+
+var (
+	// yamlLineRegexp matches the line number yaml.v2 prefixes its syntax and decoding errors with.
+	yamlLineRegexp = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+	envNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Config is the configuration of a sample, read from the config file in its directory. Every key is optional.
+type Config struct {
+	// The location of the README the build and deploy commands are parsed from, relative to the sample's directory.
+	// README.md in the sample's directory by default.
+	Readme string `yaml:"readme"`
+
+	// Where the sample is tested: TargetCloud (the default) or TargetLocal.
+	Target string `yaml:"target"`
+
+	// The container registry the sample's container image is pushed to (see gcloud.ParseRegistry).
+	Registry string `yaml:"registry"`
+
+	// The region the sample is deployed to. The run/region and functions/region gcloud properties by default.
+	Region string `yaml:"region"`
+
+	// Environment variables set for the build and deploy commands.
+	Env map[string]string `yaml:"env"`
+
+	// The location of the OpenAPI document describing the test endpoints, relative to the sample's directory.
+	Tests string `yaml:"tests"`
+
+	Timeouts Timeouts `yaml:"timeouts"`
+	Skip     Skip     `yaml:"skip"`
+	Job      Job      `yaml:"job"`
+
+	// Additional commands that clean up resources created by the build and deploy commands, e.g. a Cloud SQL
+	// instance. They're parsed like README commands and run after the tool's own resources are deleted.
+	Cleanup []string `yaml:"cleanup"`
+
+	// The location of the config file the Config was read from, or empty if the sample has none.
+	File string `yaml:"-"`
+}
+
+// Timeouts are how long the steps of testing a sample can take. A zero duration means the default.
+type Timeouts struct {
+	// How long the build and deploy commands can take altogether. Unlimited by default.
+	Deploy time.Duration `yaml:"deploy"`
+
+	// How long each test endpoint request can take. 10 seconds by default.
+	Request time.Duration `yaml:"request"`
+}
+
+// Skip lists the steps of testing a sample that are skipped.
+type Skip struct {
+	// Skip testing the sample altogether, e.g. because it's known to be broken.
+	Sample bool `yaml:"sample"`
+
+	// Skip testing the sample on the local target, e.g. because it depends on other GCP services.
+	Local bool `yaml:"local"`
+
+	// Deploy the sample and clean it up without testing its endpoints.
+	Endpoints bool `yaml:"endpoints"`
+}
+
+// Job is the expected outcome of executing a sample deployed as a Cloud Run job.
+type Job struct {
+	// The expected number of succeeded tasks. If nil, no task may fail.
+	Tasks *int `yaml:"tasks"`
+
+	// Lines that must be contained in lines logged by the execution.
+	Logs []string `yaml:"logs"`
+}
+
+// Problem is a single problem found in a config file.
+type Problem struct {
+	// The line of the config file the problem is at, or 0 if unknown.
+	Line    int
+	Message string
+}
+
+// Error is returned when a config file is invalid. It lists all the problems found in it.
+type Error struct {
+	File     string
+	Problems []Problem
+}
+
+// Error formats every problem as a file:line: message line, or as its message alone if File is empty.
+func (e *Error) Error() string {
+	var lines []string
+	for _, p := range e.Problems {
+		if e.File == "" {
+			lines = append(lines, p.Message)
+		} else if p.Line > 0 {
+			lines = append(lines, fmt.Sprintf("%s:%d: %s", e.File, p.Line, p.Message))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s: %s", e.File, p.Message))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Load reads and validates the config file of the sample located in the provided directory. A missing config file
+// isn't an error, since every key is optional: an empty Config is returned instead. If the config file is invalid, an
+// *Error is returned.
+func Load(sampleDir string) (*Config, error) {
+	c, err := Read(sampleDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Validate(sampleDir); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Loader loads the Config of the sample located in a directory.
+type Loader interface {
+	Load(sampleDir string) (*Config, error)
+}
+
+// FileLoader is a Loader that reads and validates the config file in the sample's directory with Load.
+type FileLoader struct{}
+
+// Load implements Loader.
+func (FileLoader) Load(sampleDir string) (*Config, error) {
+	return Load(sampleDir)
+}
+
+// StaticLoader is a Loader that validates its Config and returns a shallow copy of it for every sample, regardless of
+// their config files. It's useful to configure samples programmatically.
+type StaticLoader struct {
+	Config Config
+}
+
+// Load implements Loader.
+func (l StaticLoader) Load(sampleDir string) (*Config, error) {
+	c := l.Config
+	if err := c.Validate(sampleDir); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// Read reads the config file of the sample located in the provided directory like Load, without validating its values.
+func Read(sampleDir string) (*Config, error) {
+	p, err := filePath(sampleDir)
+	if err != nil || p == "" {
+		return &Config{}, err
+	}
+
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("ioutil.ReadFile: %w", err)
+	}
+
+	return Decode(bytes.NewReader(b), p)
+}
+
+// filePath returns the location of the config file of the sample located in the provided directory, named FileName
+// or AltFileName, or an empty string if the sample has none. An *Error is returned if it has both.
+func filePath(sampleDir string) (string, error) {
+	var found []string
+	for _, n := range []string{FileName, AltFileName} {
+		p := filepath.Join(sampleDir, n)
+		if _, err := os.Stat(p); err == nil {
+			found = append(found, p)
+		} else if !os.IsNotExist(err) {
+			return "", fmt.Errorf("os.Stat: %w", err)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return "", nil
+	case 1:
+		return found[0], nil
+	}
+
+	return "", &Error{File: found[1], Problems: []Problem{{Message: "conflicts with " + FileName + ": remove one of them"}}}
+}
+
+// Decode decodes a Config from the provided reader, which reads the config file with the provided name. Decoding is
+// strict: unknown and duplicate keys are problems, reported along with their line numbers in an *Error.
+func Decode(r io.Reader, filename string) (*Config, error) {
+	c := &Config{File: filename}
+
+	d := yaml.NewDecoder(r)
+	d.SetStrict(true)
+	err := d.Decode(c)
+	if err == nil || errors.Is(err, io.EOF) {
+		return c, nil
+	}
+
+	cErr := &Error{File: filename}
+	var tErr *yaml.TypeError
+	if errors.As(err, &tErr) {
+		for _, e := range tErr.Errors {
+			cErr.Problems = append(cErr.Problems, yamlProblem(e))
+		}
+	} else {
+		cErr.Problems = append(cErr.Problems, yamlProblem(err.Error()))
+	}
+
+	return nil, cErr
+}
+
+// yamlProblem converts a yaml.v2 error message to a Problem, extracting its line number, if any.
+func yamlProblem(msg string) Problem {
+	m := yamlLineRegexp.FindStringSubmatch(msg)
+	if m == nil {
+		return Problem{Message: strings.TrimPrefix(msg, "yaml: ")}
+	}
+
+	line, _ := strconv.Atoi(m[1])
+	return Problem{Line: line, Message: m[2]}
+}
+
+// Validate checks the values of the Config of the sample located in the provided directory and returns an *Error
+// listing all the invalid ones, if any. The files the Config references must exist.
+func (c *Config) Validate(sampleDir string) error {
+	cErr := &Error{File: c.File}
+	add := func(format string, a ...interface{}) {
+		cErr.Problems = append(cErr.Problems, Problem{Message: fmt.Sprintf(format, a...)})
+	}
+
+	if c.Target != "" && c.Target != TargetCloud && c.Target != TargetLocal {
+		add("target: unsupported target %q: must be %s or %s", c.Target, TargetCloud, TargetLocal)
+	}
+
+	if _, err := gcloud.ParseRegistry(c.Registry); err != nil {
+		add("registry: %v", err)
+	}
+
+	var names []string
+	for name := range c.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !envNameRegexp.MatchString(name) {
+			add("env: %q is not a valid environment variable name", name)
+		}
+	}
+
+	if c.Readme != "" {
+		if _, err := os.Stat(c.ReadmePath(sampleDir)); err != nil {
+			add("readme: %v", err)
+		}
+	}
+
+	if c.Tests != "" {
+		if _, err := os.Stat(c.TestsPath(sampleDir)); err != nil {
+			add("tests: %v", err)
+		}
+	}
+
+	if c.Timeouts.Deploy < 0 {
+		add("timeouts.deploy: must not be negative")
+	}
+
+	if c.Timeouts.Request < 0 {
+		add("timeouts.request: must not be negative")
+	}
+
+	if c.Job.Tasks != nil && *c.Job.Tasks < 0 {
+		add("job.tasks: must not be negative")
+	}
+
+	for i, cmd := range c.Cleanup {
+		if strings.TrimSpace(cmd) == "" {
+			add("cleanup[%d]: empty command", i)
+		}
+	}
+
+	if len(cErr.Problems) > 0 {
+		return cErr
+	}
+
+	return nil
+}
+
+// ReadmePath returns the location of the README the build and deploy commands of the sample located in the provided
+// directory are parsed from: the one set with the readme key, or README.md in the sample's directory.
+func (c *Config) ReadmePath(sampleDir string) string {
+	if c.Readme == "" {
+		return filepath.Join(sampleDir, "README.md")
+	}
+
+	if filepath.IsAbs(c.Readme) {
+		return c.Readme
+	}
+
+	return filepath.Join(sampleDir, c.Readme)
+}
+
+// TestsPath returns the location of the OpenAPI document describing the test endpoints of the sample located in the
+// provided directory, or empty if none is set.
+func (c *Config) TestsPath(sampleDir string) string {
+	if c.Tests == "" || filepath.IsAbs(c.Tests) {
+		return c.Tests
+	}
+
+	return filepath.Join(sampleDir, c.Tests)
+}
+
+// TargetOrDefault returns the target the sample is tested on: TargetCloud if none is set.
+func (c *Config) TargetOrDefault() string {
+	if c.Target == "" {
+		return TargetCloud
+	}
+
+	return c.Target
+}
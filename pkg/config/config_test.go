@@ -0,0 +1,206 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var loadTests = []struct {
+	name     string    // config file name, FileName by default
+	in       string    // config file contents, or empty for no config file
+	out      *Config   // expected Config, without File
+	problems []Problem // expected problems if the config file is invalid
+}{
+	// no config file
+	{
+		out: &Config{},
+	},
+
+	// every key
+	{
+		in: "readme: README.md\n" +
+			"target: local\n" +
+			"registry: us-central1-docker.pkg.dev/samples\n" +
+			"region: us-east1\n" +
+			"env:\n  GREETING: hello\n" +
+			"tests: README.md\n" +
+			"timeouts:\n  deploy: 15m\n  request: 30s\n" +
+			"skip:\n  sample: false\n  local: true\n  endpoints: true\n" +
+			"job:\n  tasks: 2\n  logs:\n  - hello\n" +
+			"cleanup:\n- gcloud sql instances delete hello-db\n",
+		out: &Config{
+			Readme:   "README.md",
+			Target:   TargetLocal,
+			Registry: "us-central1-docker.pkg.dev/samples",
+			Region:   "us-east1",
+			Env:      map[string]string{"GREETING": "hello"},
+			Tests:    "README.md",
+			Timeouts: Timeouts{Deploy: 15 * time.Minute, Request: 30 * time.Second},
+			Skip:     Skip{Local: true, Endpoints: true},
+			Job:      Job{Tasks: intPtr(2), Logs: []string{"hello"}},
+			Cleanup:  []string{"gcloud sql instances delete hello-db"},
+		},
+	},
+
+	// config file with the alternative name
+	{
+		name: AltFileName,
+		in:   "region: us-east1\n",
+		out:  &Config{Region: "us-east1"},
+	},
+
+	// empty config file
+	{
+		in:  "# nothing to configure\n",
+		out: &Config{},
+	},
+
+	// unknown keys, at any depth
+	{
+		in: "readme: README.md\n" +
+			"regoin: us-east1\n" +
+			"skip:\n  endpoint: true\n",
+		problems: []Problem{
+			{Line: 2, Message: "field regoin not found in type config.Config"},
+			{Line: 4, Message: "field endpoint not found in type config.Skip"},
+		},
+	},
+
+	// duplicate key
+	{
+		in:       "target: local\ntarget: cloud\n",
+		problems: []Problem{{Line: 2, Message: "field target already set in type config.Config"}},
+	},
+
+	// wrong type
+	{
+		in:       "timeouts:\n  deploy: forever\n",
+		problems: []Problem{{Line: 2, Message: "cannot unmarshal !!str `forever` into time.Duration"}},
+	},
+
+	// syntax error
+	{
+		in:       "env:\n  - GREETING\n  NAME: hello\n",
+		problems: []Problem{{Line: 2, Message: "did not find expected '-' indicator"}},
+	},
+
+	// invalid values
+	{
+		in: "target: cloud-run\n" +
+			"registry: docker.io\n" +
+			"env:\n  1NAME: hello\n" +
+			"readme: docs/README.md\n" +
+			"timeouts:\n  request: -1s\n" +
+			"job:\n  tasks: -1\n" +
+			"cleanup:\n- ' '\n",
+		problems: []Problem{
+			{Message: `target: unsupported target "cloud-run": must be cloud or local`},
+			{Message: "registry: "},
+			{Message: `env: "1NAME" is not a valid environment variable name`},
+			{Message: "readme: "},
+			{Message: "timeouts.request: must not be negative"},
+			{Message: "job.tasks: must not be negative"},
+			{Message: "cleanup[0]: empty command"},
+		},
+	},
+}
+
+func TestLoad(t *testing.T) {
+	for i, tc := range loadTests {
+		dir, err := ioutil.TempDir("", "sst-config")
+		if err != nil {
+			t.Fatalf("#%d: ioutil.TempDir: %v", i, err)
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(dir, "README.md"), nil, 0644); err != nil {
+			t.Fatalf("#%d: ioutil.WriteFile: %v", i, err)
+		}
+
+		name := FileName
+		if tc.name != "" {
+			name = tc.name
+		}
+
+		p := filepath.Join(dir, name)
+		if tc.in != "" {
+			if err := ioutil.WriteFile(p, []byte(tc.in), 0644); err != nil {
+				t.Fatalf("#%d: ioutil.WriteFile: %v", i, err)
+			}
+		}
+
+		c, err := Load(dir)
+		os.RemoveAll(dir)
+
+		if tc.problems == nil {
+			if err != nil {
+				t.Errorf("#%d: Load: %v", i, err)
+				continue
+			}
+
+			c.File = ""
+			if !reflect.DeepEqual(c, tc.out) {
+				t.Errorf("#%d: result mismatch\nwant: %+v\ngot: %+v", i, tc.out, c)
+			}
+			continue
+		}
+
+		cErr, ok := err.(*Error)
+		if !ok {
+			t.Errorf("#%d: error mismatch\nwant: *Error\ngot: %v", i, err)
+			continue
+		}
+
+		if cErr.File != p {
+			t.Errorf("#%d: file mismatch\nwant: %s\ngot: %s", i, p, cErr.File)
+		}
+
+		if len(cErr.Problems) != len(tc.problems) {
+			t.Errorf("#%d: problems mismatch\nwant: %v\ngot: %v", i, tc.problems, cErr.Problems)
+			continue
+		}
+
+		// Messages wrapping errors from other packages are only checked up to the wrapped error.
+		for j, want := range tc.problems {
+			got := cErr.Problems[j]
+			if got.Line != want.Line || !strings.HasPrefix(got.Message, want.Message) {
+				t.Errorf("#%d: problem %d mismatch\nwant: %v\ngot: %v", i, j, want, got)
+			}
+		}
+	}
+}
+
+func TestLoadBothFileNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sst-config")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, n := range []string{FileName, AltFileName} {
+		if err := ioutil.WriteFile(filepath.Join(dir, n), []byte("region: us-east1\n"), 0644); err != nil {
+			t.Fatalf("ioutil.WriteFile: %v", err)
+		}
+	}
+
+	_, err = Load(dir)
+	if cErr, ok := err.(*Error); !ok || cErr.File != filepath.Join(dir, AltFileName) {
+		t.Errorf("error mismatch\nwant: *Error in %s\ngot: %v", AltFileName, err)
+	}
+}
+
+func TestErrorString(t *testing.T) {
+	e := &Error{File: "config.yaml", Problems: []Problem{{Line: 2, Message: "field regoin not found"}, {Message: "readme: missing"}}}
+	want := "config.yaml:2: field regoin not found\nconfig.yaml: readme: missing"
+	if got := e.Error(); got != want {
+		t.Errorf("result mismatch\nwant: %q\ngot: %q", want, got)
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,139 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package docker runs samples locally in Docker containers.
+package docker
+
+import (
+	"context"
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/gcloud"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"net"
+	"net/http"
+	"os/exec"
+	"time"
+)
+
+// ContainerPort is the port a sample's container listens on, set in its $PORT environment variable like Cloud Run
+// does.
+const ContainerPort = 8080
+
+// How long, and how often, a Container is checked for readiness before giving up.
+var (
+	readyTimeout  = time.Minute
+	readyInterval = 500 * time.Millisecond
+)
+
+// Container represents a Docker container running a sample locally, and stores its parameters. It implements
+// gcloud.Service.
+type Container struct {
+	Name string
+
+	// The port of localhost that ContainerPort is published to.
+	Port int
+
+	// The util.Executor the external Docker CLI is called with.
+	Executor util.Executor
+
+	ready bool
+}
+
+// Describe implements gcloud.Service.
+func (c *Container) Describe() string {
+	return "local container " + c.Name
+}
+
+// Delete calls the external Docker CLI and stops and removes the Docker container associated with the current
+// Container.
+func (c *Container) Delete(sampleDir string) error {
+	_, err := util.ExecCommand(c.Executor, c.DeleteCmd(), sampleDir)
+
+	if err != nil {
+		return fmt.Errorf("removing Docker container: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCmd returns the external Docker CLI command that Delete executes.
+func (c *Container) DeleteCmd() *exec.Cmd {
+	return exec.Command("docker", "rm", "--force", c.Name)
+}
+
+// Label is a no-op: local containers aren't cleaned up by sst cleanup, so they don't need to be labeled.
+func (c *Container) Label(sampleDir string, l gcloud.RunLabels) error {
+	return nil
+}
+
+// LabelCmd returns nil, since Label doesn't execute any command.
+func (c *Container) LabelCmd(l gcloud.RunLabels) *exec.Cmd {
+	return nil
+}
+
+// RunCmd returns the external Docker CLI command that runs the provided image in the background as the Docker
+// container associated with the current Container, with $PORT set to ContainerPort and ContainerPort published to
+// Port on localhost.
+func (c *Container) RunCmd(image string) *exec.Cmd {
+	return exec.Command("docker", "run", "--detach", "--name="+c.Name, fmt.Sprintf("--env=PORT=%d", ContainerPort),
+		fmt.Sprintf("--publish=127.0.0.1:%d:%d", c.Port, ContainerPort), image)
+}
+
+// URL waits for the Docker container associated with the current Container to accept connections, and returns its
+// root URL on localhost. Since Docker accepts connections to published ports before the container listens on them,
+// the container is considered ready once it responds to an HTTP request, whatever its status code. If the context is
+// done first, an error wrapping the context's error is returned.
+func (c *Container) URL(ctx context.Context, sampleDir string) (string, error) {
+	url := fmt.Sprintf("http://localhost:%d", c.Port)
+	if c.ready {
+		return url, nil
+	}
+
+	client := &http.Client{Timeout: readyInterval}
+	deadline := time.Now().Add(readyTimeout)
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return "", fmt.Errorf("http.NewRequest: %w", err)
+		}
+
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			c.ready = true
+			return url, nil
+		}
+
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("waiting for Docker container to accept connections on port %d: %w", c.Port, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("waiting for Docker container to accept connections on port %d: %w", c.Port, ctx.Err())
+		case <-time.After(readyInterval):
+		}
+	}
+}
+
+// FreePort returns a port of localhost that's currently free, which a Container's Port can be published to.
+func FreePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("net.Listen: %w", err)
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,71 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestContainerURL(t *testing.T) {
+	readyInterval = 10 * time.Millisecond
+	readyTimeout = time.Second
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	_, p, err := net.SplitHostPort(ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("net.SplitHostPort: %v", err)
+	}
+	port, _ := strconv.Atoi(p)
+
+	c := &Container{Name: "hello", Port: port}
+	url, err := c.URL(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Container.URL: %v", err)
+	}
+
+	if want := "http://localhost:" + p; url != want {
+		t.Errorf("Container.URL: result mismatch\nwant: %s\ngot: %s", want, url)
+	}
+}
+
+func TestContainerURLTimeout(t *testing.T) {
+	readyInterval = 10 * time.Millisecond
+	readyTimeout = 50 * time.Millisecond
+
+	port, err := FreePort()
+	if err != nil {
+		t.Fatalf("FreePort: %v", err)
+	}
+
+	c := &Container{Name: "hello", Port: port}
+	if _, err := c.URL(context.Background(), ""); err == nil {
+		t.Errorf("Container.URL: got nil error for a port nothing listens on")
+	}
+}
+
+func TestContainerURLCanceled(t *testing.T) {
+	readyInterval = 10 * time.Millisecond
+	readyTimeout = time.Minute
+
+	port, err := FreePort()
+	if err != nil {
+		t.Fatalf("FreePort: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	c := &Container{Name: "hello", Port: port}
+	if _, err := c.URL(ctx, ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Container.URL: error mismatch\nwant: %v\ngot: %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcloud
+
+import (
+	"context"
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"os/exec"
+	"strings"
+)
+
+// AppEngineVersion represents a version of an App Engine service that isn't promoted to receive traffic, and stores
+// its parameters. It implements Service.
+type AppEngineVersion struct {
+	// The ID of the version. Since it's unique, the version is looked up across all of the app's services.
+	Version string
+	url     string
+
+	// The util.Executor the external gcloud SDK is called with.
+	Executor util.Executor
+}
+
+// Describe implements Service.
+func (v *AppEngineVersion) Describe() string {
+	return "App Engine version " + v.Version
+}
+
+// Delete calls the external gcloud SDK and deletes the App Engine version associated with the current
+// AppEngineVersion.
+func (v *AppEngineVersion) Delete(sampleDir string) error {
+	_, err := util.ExecCommand(v.Executor, v.DeleteCmd(), sampleDir)
+
+	if err != nil {
+		return fmt.Errorf("deleting App Engine version: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCmd returns the external gcloud SDK command that Delete executes.
+func (v *AppEngineVersion) DeleteCmd() *exec.Cmd {
+	a := append(util.GcloudCommonFlags, "app", "versions", "delete", v.Version)
+	return exec.Command("gcloud", a...)
+}
+
+// Label is a no-op: App Engine versions can't be labeled, so orphaned versions aren't found by sst cleanup.
+func (v *AppEngineVersion) Label(sampleDir string, l RunLabels) error {
+	return nil
+}
+
+// LabelCmd returns nil, since Label doesn't execute any command.
+func (v *AppEngineVersion) LabelCmd(l RunLabels) *exec.Cmd {
+	return nil
+}
+
+// URL calls the external gcloud SDK and gets the version-specific URL of the App Engine version associated with the
+// current AppEngineVersion, e.g. https://VERSION-dot-PROJECT.REGION_ID.r.appspot.com.
+func (v *AppEngineVersion) URL(ctx context.Context, sampleDir string) (string, error) {
+	if v.url != "" {
+		return v.url, nil
+	}
+
+	a := append(util.GcloudCommonFlags, "app", "versions", "list", "--filter=version.id="+v.Version,
+		"--format=value(version.versionUrl)")
+	r, err := v.Executor.Run(ctx, exec.Command("gcloud", a...), sampleDir)
+	if err != nil {
+		return "", fmt.Errorf("getting App Engine version URL: %w", err)
+	}
+
+	fields := strings.Fields(r.Stdout)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("getting App Engine version URL: version %s not found", v.Version)
+	}
+
+	v.url = fields[0]
+	return v.url, nil
+}
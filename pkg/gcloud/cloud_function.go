@@ -0,0 +1,98 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcloud
+
+import (
+	"context"
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"os/exec"
+	"strings"
+)
+
+// CloudFunction represents an HTTP-triggered Cloud Function and stores its parameters. It implements Service.
+type CloudFunction struct {
+	Name string
+	url  string
+
+	// The region the function is deployed to, or empty to use the functions/region gcloud property.
+	Region string
+
+	// The util.Executor the external gcloud SDK is called with.
+	Executor util.Executor
+}
+
+// Describe implements Service.
+func (f *CloudFunction) Describe() string {
+	return "Cloud Function " + f.Name
+}
+
+// Delete calls the external gcloud SDK and deletes the Cloud Function associated with the current CloudFunction.
+func (f *CloudFunction) Delete(sampleDir string) error {
+	_, err := util.ExecCommand(f.Executor, f.DeleteCmd(), sampleDir)
+
+	if err != nil {
+		return fmt.Errorf("deleting Cloud Function: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCmd returns the external gcloud SDK command that Delete executes.
+func (f *CloudFunction) DeleteCmd() *exec.Cmd {
+	a := append(util.GcloudCommonFlags, "functions", "delete", f.Name)
+	if f.Region != "" {
+		a = append(a, "--region="+f.Region)
+	}
+	return exec.Command("gcloud", a...)
+}
+
+// Label is a no-op: Cloud Functions can only be labeled when they're deployed, so the labels are added to the gcloud
+// functions deploy command instead.
+func (f *CloudFunction) Label(sampleDir string, l RunLabels) error {
+	return nil
+}
+
+// LabelCmd returns nil, since Label doesn't execute any command.
+func (f *CloudFunction) LabelCmd(l RunLabels) *exec.Cmd {
+	return nil
+}
+
+// URL calls the external gcloud SDK and gets the HTTPS trigger URL of the Cloud Function associated with the current
+// CloudFunction. Both 1st gen and 2nd gen functions are supported.
+func (f *CloudFunction) URL(ctx context.Context, sampleDir string) (string, error) {
+	if f.url != "" {
+		return f.url, nil
+	}
+
+	a := append(util.GcloudCommonFlags, "functions", "describe", f.Name,
+		"--format=value(httpsTrigger.url,serviceConfig.uri)")
+	if f.Region != "" {
+		a = append(a, "--region="+f.Region)
+	}
+	r, err := f.Executor.Run(ctx, exec.Command("gcloud", a...), sampleDir)
+	if err != nil {
+		return "", fmt.Errorf("getting Cloud Function URL: %w", err)
+	}
+
+	// Only one of the fields is set, depending on the generation of the function.
+	fields := strings.Fields(r.Stdout)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("getting Cloud Function URL: %s doesn't have an HTTPS trigger", f.Name)
+	}
+
+	f.url = fields[0]
+	return f.url, nil
+}
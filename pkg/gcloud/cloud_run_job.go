@@ -0,0 +1,150 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"os/exec"
+	"strings"
+)
+
+// CloudRunJob represents a Cloud Run job and stores its parameters. It implements Resource.
+type CloudRunJob struct {
+	Name string
+
+	// The region the job is deployed to, or empty to use the run/region gcloud property.
+	Region string
+
+	// The util.Executor the external gcloud SDK is called with.
+	Executor util.Executor
+}
+
+// JobExecution is the outcome of a finished execution of a Cloud Run job.
+type JobExecution struct {
+	Name           string `json:"name"`
+	TaskCount      int    `json:"taskCount"`
+	SucceededCount int    `json:"succeededCount"`
+	FailedCount    int    `json:"failedCount"`
+}
+
+// Describe implements Resource.
+func (j *CloudRunJob) Describe() string {
+	return "Cloud Run job " + j.Name
+}
+
+// Delete calls the external gcloud SDK and deletes the Cloud Run job associated with the current CloudRunJob.
+func (j *CloudRunJob) Delete(sampleDir string) error {
+	_, err := util.ExecCommand(j.Executor, j.DeleteCmd(), sampleDir)
+
+	if err != nil {
+		return fmt.Errorf("deleting Cloud Run job: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCmd returns the external gcloud SDK command that Delete executes.
+func (j *CloudRunJob) DeleteCmd() *exec.Cmd {
+	a := append(util.GcloudCommonFlags, "run", "jobs", "delete", j.Name)
+	if j.Region != "" {
+		a = append(a, "--region="+j.Region)
+	}
+	return exec.Command("gcloud", a...)
+}
+
+// Label calls the external gcloud SDK and adds the provided RunLabels to the Cloud Run job associated with the current
+// CloudRunJob.
+func (j *CloudRunJob) Label(sampleDir string, l RunLabels) error {
+	_, err := util.ExecCommand(j.Executor, j.LabelCmd(l), sampleDir)
+
+	if err != nil {
+		return fmt.Errorf("labeling Cloud Run job: %w", err)
+	}
+
+	return nil
+}
+
+// LabelCmd returns the external gcloud SDK command that Label executes.
+func (j *CloudRunJob) LabelCmd(l RunLabels) *exec.Cmd {
+	a := append(util.GcloudCommonFlags, "run", "jobs", "update", j.Name, "--update-labels="+l.ServiceLabels())
+	if j.Region != "" {
+		a = append(a, "--region="+j.Region)
+	}
+	return exec.Command("gcloud", a...)
+}
+
+// Execute calls the external gcloud SDK to execute the Cloud Run job associated with the current CloudRunJob, waits
+// for the execution to finish, and returns its outcome. Once the provided context is done, the gcloud command waiting
+// for the execution is killed.
+func (j *CloudRunJob) Execute(ctx context.Context, sampleDir string) (JobExecution, error) {
+	r, err := j.Executor.Run(ctx, j.ExecuteCmd(), sampleDir)
+	if err != nil {
+		return JobExecution{}, fmt.Errorf("executing Cloud Run job: %w", err)
+	}
+	out := r.Stdout
+
+	var e struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			TaskCount int `json:"taskCount"`
+		} `json:"spec"`
+		Status struct {
+			SucceededCount int `json:"succeededCount"`
+			FailedCount    int `json:"failedCount"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(out), &e); err != nil {
+		return JobExecution{}, fmt.Errorf("json.Unmarshal: parsing Cloud Run job execution: %w", err)
+	}
+
+	return JobExecution{
+		Name:           e.Metadata.Name,
+		TaskCount:      e.Spec.TaskCount,
+		SucceededCount: e.Status.SucceededCount,
+		FailedCount:    e.Status.FailedCount,
+	}, nil
+}
+
+// ExecuteCmd returns the external gcloud SDK command that Execute executes.
+func (j *CloudRunJob) ExecuteCmd() *exec.Cmd {
+	a := append(util.GcloudCommonFlags, "run", "jobs", "execute", j.Name, "--wait", "--format=json")
+	if j.Region != "" {
+		a = append(a, "--region="+j.Region)
+	}
+	return exec.Command("gcloud", a...)
+}
+
+// Logs calls the external gcloud SDK and returns the text log lines written by the tasks of the provided execution of
+// the Cloud Run job, oldest first. Logs can take a while to be ingested, so recent lines may be missing.
+func (j *CloudRunJob) Logs(sampleDir, execution string) ([]string, error) {
+	filter := fmt.Sprintf(`resource.type="cloud_run_job" AND resource.labels.job_name=%q AND `+
+		`labels."run.googleapis.com/execution_name"=%q`, j.Name, execution)
+	a := append(util.GcloudCommonFlags, "logging", "read", filter, "--order=asc", "--format=value(textPayload)")
+	out, err := util.ExecCommand(j.Executor, exec.Command("gcloud", a...), sampleDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading Cloud Run job logs: %w", err)
+	}
+
+	if out == "" {
+		return nil, nil
+	}
+
+	return strings.Split(out, "\n"), nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcloud
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"os/exec"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxCloudRunServiceNameLen        = 53
+	cloudRunServiceNameRandSuffixLen = 10
+)
+
+// CloudRunService represents a Cloud Run service and stores its parameters. It implements Service.
+type CloudRunService struct {
+	Name string
+	url  string
+
+	// The region the service is deployed to, or empty to use the run/region gcloud property.
+	Region string
+
+	// The util.Executor the external gcloud SDK is called with.
+	Executor util.Executor
+}
+
+// Describe implements Service.
+func (s *CloudRunService) Describe() string {
+	return "Cloud Run service " + s.Name
+}
+
+// Delete calls the external gcloud SDK and deletes the Cloud Run Service associated with the current cloudRunService.
+func (s *CloudRunService) Delete(sampleDir string) error {
+	_, err := util.ExecCommand(s.Executor, s.DeleteCmd(), sampleDir)
+
+	if err != nil {
+		return fmt.Errorf("deleting Cloud Run Service: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCmd returns the external gcloud SDK command that Delete executes.
+func (s *CloudRunService) DeleteCmd() *exec.Cmd {
+	a := append(util.GcloudCommonFlags, "run", "services", "delete", s.Name, "--platform=managed")
+	if s.Region != "" {
+		a = append(a, "--region="+s.Region)
+	}
+	return exec.Command("gcloud", a...)
+}
+
+// Label calls the external gcloud SDK and adds the provided RunLabels to the Cloud Run Service associated with the
+// current CloudRunService.
+func (s *CloudRunService) Label(sampleDir string, l RunLabels) error {
+	_, err := util.ExecCommand(s.Executor, s.LabelCmd(l), sampleDir)
+
+	if err != nil {
+		return fmt.Errorf("labeling Cloud Run Service: %w", err)
+	}
+
+	return nil
+}
+
+// LabelCmd returns the external gcloud SDK command that Label executes.
+func (s *CloudRunService) LabelCmd(l RunLabels) *exec.Cmd {
+	a := append(util.GcloudCommonFlags, "run", "services", "update", s.Name, "--platform=managed",
+		"--update-labels="+l.ServiceLabels())
+	if s.Region != "" {
+		a = append(a, "--region="+s.Region)
+	}
+	return exec.Command("gcloud", a...)
+}
+
+// URL calls the external gcloud SDK and gets the root URL of the Cloud Run Service associated with the current
+// CloudRunService.
+func (s *CloudRunService) URL(ctx context.Context, sampleDir string) (string, error) {
+	if s.url != "" {
+		return s.url, nil
+	}
+
+	a := append(util.GcloudCommonFlags, "run", "--platform=managed", "services", "describe", s.Name,
+		"--format=value(status.url)")
+	if s.Region != "" {
+		a = append(a, "--region="+s.Region)
+	}
+	r, err := s.Executor.Run(ctx, exec.Command("gcloud", a...), sampleDir)
+
+	if err != nil {
+		return "", fmt.Errorf("getting Cloud Run Service URL: %w", err)
+	}
+
+	s.url = r.Stdout
+	return s.url, nil
+}
+
+// ServiceName generates a Cloud Run service or Cloud Function name for the provided sample. It concatenates the
+// sample's name with a random alphanumeric string.
+func ServiceName(sampleName string) (string, error) {
+	randBytes := make([]byte, cloudRunServiceNameRandSuffixLen/2)
+
+	_, err := rand.Read(randBytes)
+	if err != nil {
+		return "", fmt.Errorf("crypto/rand.Read: %w", err)
+	}
+
+	randSuffix := hex.EncodeToString(randBytes)
+
+	if l := maxCloudRunServiceNameLen - len(randSuffix) - 1; len(sampleName) > l {
+		sampleName = sampleName[len(sampleName)-l:]
+	}
+	sampleName = strings.TrimFunc(sampleName, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	return sampleName + "-" + randSuffix, nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcloud
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// RunIDLabel is the label key holding the ID of the run that created a Cloud Run service.
+	RunIDLabel = "sst-run-id"
+
+	// CreatedAtLabel is the label key holding the Unix time a Cloud Run service was created at.
+	CreatedAtLabel = "sst-created-at"
+
+	// imageTagPrefix is the prefix of the tag added to every container image created by the tool. The rest of the tag
+	// is the Unix time the image was created at and the ID of the run that created it, separated by a dash.
+	imageTagPrefix = "sst-"
+
+	runIDLen = 8
+)
+
+// RunLabels identifies the resources created by a single run of the tool, so that they can be found and deleted
+// later if the run didn't clean up after itself.
+type RunLabels struct {
+	RunID     string
+	CreatedAt time.Time
+}
+
+// NewRunLabels creates RunLabels with a random run ID and the current time.
+func NewRunLabels() (RunLabels, error) {
+	b := make([]byte, runIDLen/2)
+	if _, err := rand.Read(b); err != nil {
+		return RunLabels{}, fmt.Errorf("crypto/rand.Read: %w", err)
+	}
+
+	return RunLabels{RunID: hex.EncodeToString(b), CreatedAt: time.Now()}, nil
+}
+
+// ServiceLabels formats the labels as a value for gcloud's --update-labels flag.
+func (l RunLabels) ServiceLabels() string {
+	return fmt.Sprintf("%s=%s,%s=%d", RunIDLabel, l.RunID, CreatedAtLabel, l.CreatedAt.Unix())
+}
+
+// ImageTag formats the labels as a container image tag.
+func (l RunLabels) ImageTag() string {
+	return fmt.Sprintf("%s%d-%s", imageTagPrefix, l.CreatedAt.Unix(), l.RunID)
+}
+
+// parseImageTag parses RunLabels out of a container image tag created by RunLabels.ImageTag. It returns false if the
+// tag wasn't created by the tool.
+func parseImageTag(tag string) (RunLabels, bool) {
+	if !strings.HasPrefix(tag, imageTagPrefix) {
+		return RunLabels{}, false
+	}
+
+	sp := strings.SplitN(strings.TrimPrefix(tag, imageTagPrefix), "-", 2)
+	if len(sp) != 2 {
+		return RunLabels{}, false
+	}
+
+	t, err := strconv.ParseInt(sp[0], 10, 64)
+	if err != nil {
+		return RunLabels{}, false
+	}
+
+	return RunLabels{RunID: sp[1], CreatedAt: time.Unix(t, 0)}, true
+}
+
+// parseCreatedAtLabel parses the value of a CreatedAtLabel.
+func parseCreatedAtLabel(v string) (time.Time, error) {
+	t, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("strconv.ParseInt: %s label: %w", CreatedAtLabel, err)
+	}
+
+	return time.Unix(t, 0), nil
+}
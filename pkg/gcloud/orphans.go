@@ -0,0 +1,334 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"os/exec"
+	"strings"
+)
+
+// locationLabel is the label Cloud Run adds to every service and job with the region it's located in.
+const locationLabel = "cloud.googleapis.com/location"
+
+// LabeledService is a Cloud Run service that was created and labeled by the tool.
+type LabeledService struct {
+	Name   string
+	Region string
+	Labels RunLabels
+}
+
+// LabeledJob is a Cloud Run job that was created and labeled by the tool.
+type LabeledJob struct {
+	Name   string
+	Region string
+	Labels RunLabels
+}
+
+// LabeledFunction is a Cloud Function that was deployed and labeled by the tool.
+type LabeledFunction struct {
+	Name   string
+	Region string
+	Labels RunLabels
+}
+
+// LabeledImage is a container image that was created and tagged by the tool.
+type LabeledImage struct {
+	// The name of the image without a tag or digest, e.g. gcr.io/project/image.
+	Name string
+
+	// The digest of the image, e.g. sha256:...
+	Digest string
+
+	Labels RunLabels
+
+	// The Registry the image is stored in.
+	Registry Registry
+}
+
+// ListLabeledServices calls the external gcloud SDK and lists the Cloud Run services in all regions of the default
+// project that carry the labels the tool adds to the services it creates.
+func ListLabeledServices(e util.Executor) ([]LabeledService, error) {
+	return listLabeledRunResources(e, "Cloud Run services", "services", "list", "--platform=managed")
+}
+
+// ListLabeledJobs calls the external gcloud SDK and lists the Cloud Run jobs in all regions of the default project
+// that carry the labels the tool adds to the jobs it creates.
+func ListLabeledJobs(e util.Executor) ([]LabeledJob, error) {
+	resources, err := listLabeledRunResources(e, "Cloud Run jobs", "jobs", "list")
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []LabeledJob
+	for _, r := range resources {
+		jobs = append(jobs, LabeledJob(r))
+	}
+
+	return jobs, nil
+}
+
+// listLabeledRunResources lists the Cloud Run resources listed by the gcloud run command with the provided arguments
+// that carry the labels the tool adds to the resources it creates. The resources are described by the provided
+// description in errors.
+func listLabeledRunResources(e util.Executor, description string, args ...string) ([]LabeledService, error) {
+	a := append(append(util.GcloudCommonFlags, "run"), args...)
+	a = append(a, "--format=json")
+	out, err := util.ExecCommand(e, exec.Command("gcloud", a...), "")
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", description, err)
+	}
+
+	var items []struct {
+		Metadata struct {
+			Name   string            `json:"name"`
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal([]byte(out), &items); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %s list: %w", description, err)
+	}
+
+	var resources []LabeledService
+	for _, item := range items {
+		labels := item.Metadata.Labels
+		runID, ok := labels[RunIDLabel]
+		if !ok {
+			continue
+		}
+
+		createdAt, err := parseCreatedAtLabel(labels[CreatedAtLabel])
+		if err != nil {
+			return nil, fmt.Errorf("gcloud.parseCreatedAtLabel: %s %s: %w", description, item.Metadata.Name, err)
+		}
+
+		resources = append(resources, LabeledService{
+			Name:   item.Metadata.Name,
+			Region: labels[locationLabel],
+			Labels: RunLabels{RunID: runID, CreatedAt: createdAt},
+		})
+	}
+
+	return resources, nil
+}
+
+// Delete calls the external gcloud SDK and deletes the Cloud Run service.
+func (s LabeledService) Delete(e util.Executor) error {
+	if _, err := util.ExecCommand(e, s.DeleteCmd(), ""); err != nil {
+		return fmt.Errorf("deleting Cloud Run Service: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCmd returns the external gcloud SDK command that Delete executes.
+func (s LabeledService) DeleteCmd() *exec.Cmd {
+	a := append(util.GcloudCommonFlags, "run", "services", "delete", s.Name, "--platform=managed")
+	if s.Region != "" {
+		a = append(a, "--region="+s.Region)
+	}
+
+	return exec.Command("gcloud", a...)
+}
+
+// Delete calls the external gcloud SDK and deletes the Cloud Run job.
+func (j LabeledJob) Delete(e util.Executor) error {
+	if _, err := util.ExecCommand(e, j.DeleteCmd(), ""); err != nil {
+		return fmt.Errorf("deleting Cloud Run job: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCmd returns the external gcloud SDK command that Delete executes.
+func (j LabeledJob) DeleteCmd() *exec.Cmd {
+	a := append(util.GcloudCommonFlags, "run", "jobs", "delete", j.Name)
+	if j.Region != "" {
+		a = append(a, "--region="+j.Region)
+	}
+
+	return exec.Command("gcloud", a...)
+}
+
+// ListLabeledFunctions calls the external gcloud SDK and lists the Cloud Functions in all regions of the default
+// project that carry the labels the tool adds to the functions it deploys.
+func ListLabeledFunctions(e util.Executor) ([]LabeledFunction, error) {
+	a := append(util.GcloudCommonFlags, "functions", "list", "--format=json")
+	out, err := util.ExecCommand(e, exec.Command("gcloud", a...), "")
+	if err != nil {
+		return nil, fmt.Errorf("listing Cloud Functions: %w", err)
+	}
+
+	var items []struct {
+		// The function's full resource name, e.g. projects/project/locations/us-central1/functions/hello.
+		Name   string            `json:"name"`
+		Labels map[string]string `json:"labels"`
+	}
+	if err := json.Unmarshal([]byte(out), &items); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: Cloud Functions list: %w", err)
+	}
+
+	var functions []LabeledFunction
+	for _, item := range items {
+		runID, ok := item.Labels[RunIDLabel]
+		if !ok {
+			continue
+		}
+
+		createdAt, err := parseCreatedAtLabel(item.Labels[CreatedAtLabel])
+		if err != nil {
+			return nil, fmt.Errorf("gcloud.parseCreatedAtLabel: Cloud Function %s: %w", item.Name, err)
+		}
+
+		f := LabeledFunction{Name: item.Name, Labels: RunLabels{RunID: runID, CreatedAt: createdAt}}
+		sp := strings.Split(item.Name, "/")
+		if len(sp) == 6 && sp[2] == "locations" {
+			f.Name, f.Region = sp[5], sp[3]
+		}
+
+		functions = append(functions, f)
+	}
+
+	return functions, nil
+}
+
+// Delete calls the external gcloud SDK and deletes the Cloud Function.
+func (f LabeledFunction) Delete(e util.Executor) error {
+	if _, err := util.ExecCommand(e, f.DeleteCmd(), ""); err != nil {
+		return fmt.Errorf("deleting Cloud Function: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCmd returns the external gcloud SDK command that Delete executes.
+func (f LabeledFunction) DeleteCmd() *exec.Cmd {
+	a := append(util.GcloudCommonFlags, "functions", "delete", f.Name)
+	if f.Region != "" {
+		a = append(a, "--region="+f.Region)
+	}
+
+	return exec.Command("gcloud", a...)
+}
+
+// ListLabeledImages calls the external gcloud SDK and lists the container images in the provided project's registry
+// that carry the tag the tool adds to the images it creates.
+func ListLabeledImages(e util.Executor, r Registry, project string) ([]LabeledImage, error) {
+	if r.IsArtifactRegistry() {
+		return listLabeledArtifactRegistryImages(e, r, project)
+	}
+
+	a := append(util.GcloudCommonFlags, "container", "images", "list", "--repository="+r.RepositoryURL(project),
+		"--format=value(name)")
+	out, err := util.ExecCommand(e, exec.Command("gcloud", a...), "")
+	if err != nil {
+		return nil, fmt.Errorf("listing container images: %w", err)
+	}
+
+	var images []LabeledImage
+	for _, name := range strings.Fields(out) {
+		a := append(util.GcloudCommonFlags, "container", "images", "list-tags", name, "--format=json")
+		out, err := util.ExecCommand(e, exec.Command("gcloud", a...), "")
+		if err != nil {
+			return nil, fmt.Errorf("listing container image %s tags: %w", name, err)
+		}
+
+		var digests []struct {
+			Digest string   `json:"digest"`
+			Tags   []string `json:"tags"`
+		}
+		if err := json.Unmarshal([]byte(out), &digests); err != nil {
+			return nil, fmt.Errorf("json.Unmarshal: container image %s tags: %w", name, err)
+		}
+
+		for _, d := range digests {
+			for _, t := range d.Tags {
+				if l, ok := parseImageTag(t); ok {
+					images = append(images, LabeledImage{Name: name, Digest: d.Digest, Labels: l, Registry: r})
+					break
+				}
+			}
+		}
+	}
+
+	return images, nil
+}
+
+// listLabeledArtifactRegistryImages is the ListLabeledImages implementation for Artifact Registry repositories.
+func listLabeledArtifactRegistryImages(e util.Executor, r Registry, project string) ([]LabeledImage, error) {
+	a := append(util.GcloudCommonFlags, "artifacts", "docker", "images", "list", r.RepositoryURL(project),
+		"--include-tags", "--format=json")
+	out, err := util.ExecCommand(e, exec.Command("gcloud", a...), "")
+	if err != nil {
+		return nil, fmt.Errorf("listing container images: %w", err)
+	}
+
+	var versions []struct {
+		Package string          `json:"package"`
+		Version string          `json:"version"`
+		Tags    json.RawMessage `json:"tags"`
+	}
+	if err := json.Unmarshal([]byte(out), &versions); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: container images list: %w", err)
+	}
+
+	var images []LabeledImage
+	for _, v := range versions {
+		// Depending on the gcloud version, tags are listed either as an array or as a comma-separated string.
+		var tags []string
+		if err := json.Unmarshal(v.Tags, &tags); err != nil {
+			var t string
+			if err := json.Unmarshal(v.Tags, &t); err == nil && t != "" {
+				tags = strings.Split(t, ",")
+			}
+		}
+
+		for _, t := range tags {
+			if l, ok := parseImageTag(strings.TrimSpace(t)); ok {
+				images = append(images, LabeledImage{Name: v.Package, Digest: v.Version, Labels: l, Registry: r})
+				break
+			}
+		}
+	}
+
+	return images, nil
+}
+
+// Delete calls the external gcloud SDK and deletes the container image, along with all of its tags.
+func (i LabeledImage) Delete(e util.Executor) error {
+	if _, err := util.ExecCommand(e, i.DeleteCmd(), ""); err != nil {
+		return fmt.Errorf("deleting container image: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCmd returns the external gcloud SDK command that Delete executes.
+func (i LabeledImage) DeleteCmd() *exec.Cmd {
+	return i.Registry.DeleteImageCmd(i.Name + "@" + i.Digest)
+}
+
+// Project calls the external gcloud SDK and gets the default project.
+func Project(e util.Executor, dir string) (string, error) {
+	a := append(util.GcloudCommonFlags, "config", "get-value", "core/project")
+	p, err := util.ExecCommand(e, exec.Command("gcloud", a...), dir)
+	if err != nil {
+		return "", fmt.Errorf("getting gcloud default project: %w", err)
+	}
+
+	return p, nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcloud
+
+import (
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// DefaultRegistry is the Registry container images are pushed to if none is configured.
+const DefaultRegistry = "gcr.io"
+
+// artifactRegistryHostSuffix is the suffix of the hosts of Artifact Registry Docker repositories. The hosts are
+// prefixed with the location of the repository, e.g. us-central1-docker.pkg.dev.
+const artifactRegistryHostSuffix = "-docker.pkg.dev"
+
+var (
+	containerRegistryHostRegexp = regexp.MustCompile(`^([a-z]+\.)?gcr\.io$`)
+	artifactRegistryHostRegexp  = regexp.MustCompile(`^[a-z0-9-]+` + regexp.QuoteMeta(artifactRegistryHostSuffix) + `$`)
+	repositoryNameRegexp        = regexp.MustCompile(`^[a-z]([a-z0-9-]*[a-z0-9])?$`)
+)
+
+// Registry is the container registry the container images built by the tool are pushed to: either a Container
+// Registry host (e.g. gcr.io or eu.gcr.io), or an Artifact Registry Docker repository (e.g. samples in
+// us-central1-docker.pkg.dev). Images are pushed to the default project's registry.
+type Registry struct {
+	// The host of the registry, e.g. gcr.io or us-central1-docker.pkg.dev.
+	Host string
+
+	// The name of the Artifact Registry repository. Empty for Container Registry.
+	Repository string
+}
+
+// ParseRegistry parses a registry in the form HOST for Container Registry, e.g. us.gcr.io, or HOST/REPOSITORY for
+// Artifact Registry, e.g. us-central1-docker.pkg.dev/samples. An empty string is parsed as DefaultRegistry.
+func ParseRegistry(s string) (Registry, error) {
+	if s == "" {
+		s = DefaultRegistry
+	}
+
+	sp := strings.SplitN(s, "/", 2)
+	switch {
+	case containerRegistryHostRegexp.MatchString(sp[0]):
+		if len(sp) != 1 {
+			return Registry{}, fmt.Errorf("Container Registry %s: unexpected repository %s", sp[0], sp[1])
+		}
+
+		return Registry{Host: sp[0]}, nil
+
+	case artifactRegistryHostRegexp.MatchString(sp[0]):
+		if len(sp) != 2 || !repositoryNameRegexp.MatchString(sp[1]) {
+			return Registry{}, fmt.Errorf("Artifact Registry %s: expecting a repository name in the form %s/REPOSITORY", sp[0], sp[0])
+		}
+
+		return Registry{Host: sp[0], Repository: sp[1]}, nil
+	}
+
+	return Registry{}, fmt.Errorf("unsupported registry %s: expecting a Container Registry host (e.g. gcr.io) or an "+
+		"Artifact Registry repository (e.g. us-central1-docker.pkg.dev/REPOSITORY)", s)
+}
+
+// String formats the Registry in the form ParseRegistry parses.
+func (r Registry) String() string {
+	if r.Repository == "" {
+		return r.Host
+	}
+
+	return r.Host + "/" + r.Repository
+}
+
+// IsArtifactRegistry reports whether the Registry is an Artifact Registry repository.
+func (r Registry) IsArtifactRegistry() bool {
+	return r.Repository != ""
+}
+
+// Location returns the location of an Artifact Registry repository, e.g. us-central1, or an empty string for
+// Container Registry.
+func (r Registry) Location() string {
+	if !r.IsArtifactRegistry() {
+		return ""
+	}
+
+	return strings.TrimSuffix(r.Host, artifactRegistryHostSuffix)
+}
+
+// RepositoryURL returns the URL that the container images of the provided project are pushed under, e.g.
+// gcr.io/project or us-central1-docker.pkg.dev/project/samples.
+func (r Registry) RepositoryURL(project string) string {
+	u := r.Host + "/" + project
+	if r.IsArtifactRegistry() {
+		u += "/" + r.Repository
+	}
+
+	return u
+}
+
+// ImageURL returns the URL of the container image with the provided name in the provided project's registry.
+func (r Registry) ImageURL(project, name string) string {
+	return r.RepositoryURL(project) + "/" + name
+}
+
+// AddTagCmd returns the external gcloud SDK command that adds the provided tag to the container image with the
+// provided URL.
+func (r Registry) AddTagCmd(imageURL, tag string) *exec.Cmd {
+	if r.IsArtifactRegistry() {
+		a := append(util.GcloudCommonFlags, "artifacts", "docker", "tags", "add", imageURL, imageURL+":"+tag)
+		return exec.Command("gcloud", a...)
+	}
+
+	a := append(util.GcloudCommonFlags, "container", "images", "add-tag", imageURL, imageURL+":"+tag)
+	return exec.Command("gcloud", a...)
+}
+
+// DeleteImageCmd returns the external gcloud SDK command that deletes the container image with the provided URL or
+// URL@digest reference, along with all of its tags.
+func (r Registry) DeleteImageCmd(image string) *exec.Cmd {
+	if r.IsArtifactRegistry() {
+		a := append(util.GcloudCommonFlags, "artifacts", "docker", "images", "delete", image, "--delete-tags")
+		return exec.Command("gcloud", a...)
+	}
+
+	a := append(util.GcloudCommonFlags, "container", "images", "delete", image, "--force-delete-tags")
+	return exec.Command("gcloud", a...)
+}
@@ -0,0 +1,77 @@
+package gcloud
+
+import (
+	"reflect"
+	"testing"
+)
+
+type parseRegistryTest struct {
+	in       string   // input registry string
+	registry Registry // expected result of ParseRegistry
+	imageURL string   // expected result of Registry.ImageURL for project "project" and image "image"
+	err      bool     // whether ParseRegistry is expected to return an error
+}
+
+var parseRegistryTests = []parseRegistryTest{
+	// default registry
+	{
+		in:       "",
+		registry: Registry{Host: "gcr.io"},
+		imageURL: "gcr.io/project/image",
+	},
+
+	// regional Container Registry host
+	{
+		in:       "eu.gcr.io",
+		registry: Registry{Host: "eu.gcr.io"},
+		imageURL: "eu.gcr.io/project/image",
+	},
+
+	// Artifact Registry repository
+	{
+		in:       "us-central1-docker.pkg.dev/samples",
+		registry: Registry{Host: "us-central1-docker.pkg.dev", Repository: "samples"},
+		imageURL: "us-central1-docker.pkg.dev/project/samples/image",
+	},
+
+	// Container Registry host with a repository
+	{
+		in:  "gcr.io/samples",
+		err: true,
+	},
+
+	// Artifact Registry host without a repository
+	{
+		in:  "us-central1-docker.pkg.dev",
+		err: true,
+	},
+
+	// unsupported registry
+	{
+		in:  "docker.io/library",
+		err: true,
+	},
+}
+
+func TestParseRegistry(t *testing.T) {
+	for i, tc := range parseRegistryTests {
+		r, err := ParseRegistry(tc.in)
+
+		if (err != nil) != tc.err {
+			t.Errorf("#%d: error mismatch\nwant error: %t\ngot: %v", i, tc.err, err)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if !reflect.DeepEqual(r, tc.registry) {
+			t.Errorf("#%d: result mismatch\nwant: %#+v\ngot: %#+v", i, tc.registry, r)
+		}
+
+		if u := r.ImageURL("project", "image"); u != tc.imageURL {
+			t.Errorf("#%d: image URL mismatch\nwant: %s\ngot: %s", i, tc.imageURL, u)
+		}
+	}
+}
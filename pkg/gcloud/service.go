@@ -0,0 +1,49 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gcloud manages the GCP resources samples are deployed as, and their container images, with the gcloud SDK.
+package gcloud
+
+import (
+	"context"
+	"os/exec"
+)
+
+// Resource is a resource a sample is deployed as, e.g. a Cloud Run service, a Cloud Function, or a Cloud Run job.
+type Resource interface {
+	// Describe returns a short human-readable description of the resource, e.g. "Cloud Run service hello-1a2b3c4d5e".
+	Describe() string
+
+	// Delete calls the external gcloud SDK and deletes the resource.
+	Delete(sampleDir string) error
+
+	// DeleteCmd returns the external gcloud SDK command that Delete executes.
+	DeleteCmd() *exec.Cmd
+
+	// Label calls the external gcloud SDK and adds the provided RunLabels to the resource.
+	Label(sampleDir string, l RunLabels) error
+
+	// LabelCmd returns the external gcloud SDK command that Label executes, or nil if the resource can't be labeled
+	// after it's deployed.
+	LabelCmd(l RunLabels) *exec.Cmd
+}
+
+// Service is a deployed Resource that serves a sample over HTTP, e.g. a Cloud Run service or a Cloud Function.
+type Service interface {
+	Resource
+
+	// URL calls the external gcloud SDK and gets the root URL the service is served at. If the context is done first,
+	// an error wrapping the context's error is returned.
+	URL(ctx context.Context, sampleDir string) (string, error)
+}
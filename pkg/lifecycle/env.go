@@ -0,0 +1,187 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Env is the environment and working directory the commands of a Lifecycle are executed in. It's carried across all
+// the commands of a Lifecycle, and modified by the export, unset, and cd built-ins.
+type Env struct {
+	// The working directory commands are executed in.
+	Dir string
+
+	vars map[string]string
+}
+
+// NewEnv returns an Env with the provided working directory, initialized with the tester's environment variables.
+func NewEnv(dir string) *Env {
+	env := &Env{Dir: dir, vars: make(map[string]string)}
+	for _, kv := range os.Environ() {
+		sp := strings.SplitN(kv, "=", 2)
+		if len(sp) == 2 && sp[0] != "" {
+			env.vars[sp[0]] = sp[1]
+		}
+	}
+
+	return env
+}
+
+// Getenv returns the value of the environment variable with the provided name, or an empty string if it isn't set.
+func (env *Env) Getenv(name string) string {
+	return env.vars[name]
+}
+
+// LookupEnv returns the value of the environment variable with the provided name, and reports whether it's set.
+func (env *Env) LookupEnv(name string) (string, bool) {
+	v, ok := env.vars[name]
+	return v, ok
+}
+
+// LookPath searches for an executable named file in the directories of the Env's PATH, like exec.LookPath does in the
+// tester's own PATH, so that the directories the commands add to it, e.g. with export PATH=$PATH:bin, are searched
+// too. Relative directories are relative to the Env's working directory. Names containing a path separator aren't
+// searched for, and are returned as is.
+func (env *Env) LookPath(file string) (string, error) {
+	if strings.ContainsRune(file, filepath.Separator) || strings.ContainsRune(file, '/') {
+		return file, nil
+	}
+
+	for _, dir := range filepath.SplitList(env.Getenv("PATH")) {
+		if dir == "" {
+			dir = "."
+		}
+
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(env.Dir, dir)
+		}
+
+		// exec.LookPath checks paths containing a separator directly, trying the PATHEXT extensions on Windows.
+		if p, err := exec.LookPath(filepath.Join(dir, file)); err == nil {
+			return p, nil
+		}
+	}
+
+	return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+}
+
+// Setenv sets the value of the environment variable with the provided name.
+func (env *Env) Setenv(name, value string) {
+	env.vars[name] = value
+}
+
+// Unsetenv unsets the environment variable with the provided name.
+func (env *Env) Unsetenv(name string) {
+	delete(env.vars, name)
+}
+
+// Environ returns the environment variables in the form "key=value", sorted by key.
+func (env *Env) Environ() []string {
+	var e []string
+	for k, v := range env.vars {
+		e = append(e, k+"="+v)
+	}
+
+	sort.Strings(e)
+	return e
+}
+
+// builtin applies the command with the provided arguments to env if it's one of the export, unset, or cd built-ins.
+// It reports whether the command was a built-in, along with the error applying it, if any.
+func (env *Env) builtin(args []string) (bool, error) {
+	switch args[0] {
+	case "export":
+		return true, env.export(args[1:])
+	case "unset":
+		return true, env.unset(args[1:])
+	case "cd":
+		return true, env.cd(args[1:])
+	}
+
+	return false, nil
+}
+
+// export implements the export built-in. Each argument is either a name=value assignment, or the name of a variable
+// that's already set.
+func (env *Env) export(args []string) error {
+	for _, a := range args {
+		sp := strings.SplitN(a, "=", 2)
+		if !isName([]rune(sp[0])) {
+			return fmt.Errorf("export: %q: not a valid identifier", a)
+		}
+
+		if len(sp) == 2 {
+			env.Setenv(sp[0], sp[1])
+		}
+	}
+
+	return nil
+}
+
+// unset implements the unset built-in. Each argument is the name of a variable to unset.
+func (env *Env) unset(args []string) error {
+	for _, a := range args {
+		if a == "-v" {
+			continue
+		}
+
+		if !isName([]rune(a)) {
+			return fmt.Errorf("unset: %q: not a valid identifier", a)
+		}
+
+		env.Unsetenv(a)
+	}
+
+	return nil
+}
+
+// cd implements the cd built-in. Its argument is the directory to change to, relative to the current working
+// directory. Without an argument, it changes to $HOME.
+func (env *Env) cd(args []string) error {
+	var dir string
+	switch len(args) {
+	case 0:
+		dir = env.Getenv("HOME")
+		if dir == "" {
+			return fmt.Errorf("cd: HOME not set")
+		}
+	case 1:
+		dir = args[0]
+	default:
+		return fmt.Errorf("cd: too many arguments")
+	}
+
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(env.Dir, dir)
+	}
+
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("cd: os.Stat: %w", err)
+	}
+
+	if !fi.IsDir() {
+		return fmt.Errorf("cd: %s: not a directory", dir)
+	}
+
+	env.Dir = dir
+	return nil
+}
@@ -0,0 +1,373 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lifecycle parses and executes the commands that build and deploy a sample, read from its README or defaulted.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/config"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/docker"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Descriptions of the Source of the commands in the default lifecycles.
+const (
+	defaultLifecycleDescription          = "default lifecycle"
+	defaultJavaLifecycleDescription      = "default java lifecycle"
+	defaultAppEngineLifecycleDescription = "default App Engine lifecycle"
+	localLifecycleDescription            = "local lifecycle"
+)
+
+// Lifecycle is a list of ordered Commands that should be run to execute a certain process.
+type Lifecycle []Command
+
+// Command is a single exec.Cmd of a Lifecycle along with the Source it was created from.
+type Command struct {
+	Cmd    *exec.Cmd
+	Source Source
+
+	// line is the README command line the Command was parsed from, with line continuations joined, if any.
+	line string
+
+	// argv, if not nil, returns the arguments of the command to execute, with environment variables expanded with the
+	// provided mapping. It's set for commands parsed from a README, whose environment variables are expanded against
+	// the Lifecycle's Env when they're executed.
+	argv func(mapping func(string) string) ([]string, error)
+}
+
+// resolve returns the exec.Cmd to execute for the Command in the provided Env. Its environment variables are expanded
+// against env, and it inherits env's environment variables in addition to the ones set in Cmd.Env. If the Command is a
+// built-in (see Env.builtin), it's applied to env instead and a nil exec.Cmd is returned.
+func (c Command) resolve(env *Env) (*exec.Cmd, error) {
+	a := c.Cmd.Args
+	if c.argv != nil {
+		var err error
+		if a, err = c.argv(env.Getenv); err != nil {
+			return nil, err
+		}
+
+		if len(a) == 0 {
+			return nil, nil
+		}
+	}
+
+	if ok, err := env.builtin(a); ok {
+		return nil, err
+	}
+
+	// exec.Command looks the executable up in the tester's own PATH, which misses the directories added to the Env's.
+	// If it isn't found in either, starting the command fails.
+	cmd := exec.Command(a[0], a[1:]...)
+	if p, err := env.LookPath(a[0]); err == nil {
+		cmd = &exec.Cmd{Path: p, Args: a}
+	}
+
+	cmd.Env = append(env.Environ(), c.Cmd.Env...)
+	return cmd, nil
+}
+
+// Source describes where a Command came from: either lines of a README file, or a description of the default
+// lifecycle that was used.
+type Source struct {
+	// The README file the command was parsed from, if any.
+	File string
+
+	// The index of the code block the command was parsed from among the code blocks of File annotated by the code tag.
+	Block int
+
+	// The line number the command starts at in File.
+	Line int
+
+	// The line number the command ends at in File, including line continuations.
+	EndLine int
+
+	// The original text of the command in File, before any expansions or replacements.
+	Text string
+
+	// A description of the source if the command wasn't parsed from a README.
+	Description string
+}
+
+// String formats the Source as a file:line location if the command was parsed from a README, or as its description
+// otherwise.
+func (s Source) String() string {
+	if s.File == "" {
+		return s.Description
+	}
+
+	return fmt.Sprintf("%s:%d", filepath.Base(s.File), s.Line)
+}
+
+// Execute executes the commands of a lifecycle in the provided Env with the provided util.Executor. The export, unset,
+// and cd built-ins modify env instead of being executed, so their effects carry over to all the subsequent commands.
+// It stops at the first command that fails, or once the context is done. The results of all the executed commands,
+// including the failed one, are returned.
+func (l Lifecycle) Execute(ctx context.Context, e util.Executor, env *Env) ([]util.CommandResult, error) {
+	var results []util.CommandResult
+	for _, c := range l {
+		if c.Cmd == nil {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return results, fmt.Errorf("executing Lifecycle command from %s: %w", c.Source, err)
+		}
+
+		cmd, err := c.resolve(env)
+		if err != nil {
+			return results, fmt.Errorf("executing Lifecycle command from %s: %w", c.Source, err)
+		}
+
+		if cmd == nil {
+			continue
+		}
+
+		r, err := e.Run(ctx, cmd, env.Dir)
+		r.Source = c.Source.String()
+		results = append(results, r)
+		if err != nil {
+			return results, fmt.Errorf("executing Lifecycle command from %s: %w", c.Source, err)
+		}
+	}
+
+	return results, nil
+}
+
+// DeploysCloudFunction reports whether the lifecycle deploys the sample to Cloud Functions with a gcloud functions
+// deploy command rather than to Cloud Run.
+func (l Lifecycle) DeploysCloudFunction() bool {
+	return l.hasGcloudCommand("functions", "deploy")
+}
+
+// DeploysCloudRunJob reports whether the lifecycle deploys the sample as a Cloud Run job with a gcloud run jobs deploy
+// or gcloud run jobs create command rather than as a Cloud Run service.
+func (l Lifecycle) DeploysCloudRunJob() bool {
+	return l.hasGcloudCommand("jobs", "deploy", "create")
+}
+
+// DeploysAppEngine reports whether the lifecycle deploys the sample to App Engine with a gcloud app deploy command
+// rather than to Cloud Run.
+func (l Lifecycle) DeploysAppEngine() bool {
+	return l.hasGcloudCommand("app", "deploy")
+}
+
+// AddsLabels reports whether any of the Lifecycle's commands add the provided labels, formatted as a gcloud --labels
+// flag value, to the resources they deploy (see NewLifecycle).
+func (l Lifecycle) AddsLabels(labels string) bool {
+	if labels == "" {
+		return false
+	}
+
+	for _, c := range l {
+		for _, a := range c.Cmd.Args {
+			if strings.Contains(a, labels) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// hasGcloudCommand reports whether any of the lifecycle's commands, as written in its Source (or as it will be
+// executed, for the default lifecycles), is a gcloud command of the provided command group containing one of the
+// provided verbs.
+func (l Lifecycle) hasGcloudCommand(group string, verbs ...string) bool {
+	for _, c := range l {
+		text := strings.ReplaceAll(c.Source.Text, string(bashLineContChar)+"\n", " ")
+		lines := strings.Split(text, "\n")
+		if text == "" && c.Cmd != nil {
+			lines = []string{util.CommandLine(c.Cmd)}
+		}
+
+		for _, line := range lines {
+			words, err := splitWords(line, nil)
+			if err != nil || !isGcloudCommand(words, group) {
+				continue
+			}
+
+			for _, w := range words {
+				for _, v := range verbs {
+					if w == v {
+						return true
+					}
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// NewLifecycle tries to parse the build and deploy commands of the sample located in the provided directory from the
+// README located with the provided sample's config.Config. If it has none, it falls back to reasonable defaults based
+// on whether the sample is an App Engine app (has an app.yaml), or is java-based (has a pom.xml) that doesn't have a
+// Dockerfile or isn't. The provided labels, formatted as a gcloud --labels flag value, are added to the commands that
+// deploy Cloud Run services or jobs or Cloud Functions, so that the resources are labeled as soon as they're created.
+func NewLifecycle(sampleDir, serviceName, imageURL, labels string, cfg *config.Config) (Lifecycle, error) {
+	readmePath := cfg.ReadmePath(sampleDir)
+	if _, err := os.Stat(readmePath); err == nil {
+		lifecycle, err := parseREADME(readmePath, serviceName, imageURL, labels)
+		// Show README location
+		log.Println("README.md location: " + readmePath)
+		if err == nil {
+			log.Println("Using build and deploy commands found in README.md")
+			return lifecycle, nil
+		}
+
+		if !errors.Is(err, errNoReadmeCodeBlocksFound) {
+			return nil, fmt.Errorf("lifecycle.parseREADME: %s: %w", readmePath, err)
+		}
+
+		log.Printf("No code blocks immediately preceded by %s found in README.md\n", codeTag)
+	} else {
+		log.Println("No README.md found")
+	}
+
+	if _, err := os.Stat(filepath.Join(sampleDir, "app.yaml")); err == nil {
+		log.Println("Using default deploy commands for App Engine samples")
+		return buildDefaultAppEngineLifecycle(serviceName), nil
+	}
+
+	pomPath := filepath.Join(sampleDir, "pom.xml")
+	dockerfilePath := filepath.Join(sampleDir, "Dockerfile")
+
+	_, err := os.Stat(pomPath)
+	pomE := err == nil
+
+	_, err = os.Stat(dockerfilePath)
+	dockerfileE := err == nil
+
+	if pomE && !dockerfileE {
+		log.Println("Using default build and deploy commands for java samples without a Dockerfile")
+		return buildDefaultJavaLifecycle(serviceName, imageURL, labels), nil
+	}
+
+	log.Println("Using default build and deploy commands for non-java samples or java samples with a Dockerfile")
+	return buildDefaultLifecycle(serviceName, imageURL, labels), nil
+}
+
+// NewLocalLifecycle builds a build and run command lifecycle for testing the sample located in the provided directory
+// locally, without GCP. It builds the sample's container image under the provided local image name with `docker build`,
+// or with `com.google.cloud.tools:jib-maven-plugin:2.0.0:dockerBuild` for java samples without a Dockerfile, and runs
+// it as the provided docker.Container. Build and deploy commands found in the sample's README aren't used, since they
+// deploy the sample to GCP.
+func NewLocalLifecycle(sampleDir, image string, c *docker.Container) Lifecycle {
+	build := exec.Command("docker", "build", "--tag="+image, ".")
+
+	_, err := os.Stat(filepath.Join(sampleDir, "pom.xml"))
+	pomE := err == nil
+
+	_, err = os.Stat(filepath.Join(sampleDir, "Dockerfile"))
+	dockerfileE := err == nil
+
+	if pomE && !dockerfileE {
+		build = exec.Command("mvn",
+			"compile",
+			"com.google.cloud.tools:jib-maven-plugin:2.0.0:dockerBuild",
+			fmt.Sprintf("-Dimage=%s", image),
+		)
+	}
+
+	src := Source{Description: localLifecycleDescription}
+	return Lifecycle{
+		{Cmd: build, Source: src},
+		{Cmd: c.RunCmd(image), Source: src},
+	}
+}
+
+// NewCleanupLifecycle parses the provided additional cleanup commands, read from the cleanup key of the config file
+// with the provided name, if any, into a Lifecycle. They're parsed like the commands of a README code block without a
+// shell: environment variables are expanded, and the Cloud Run service name and container image URL are replaced with
+// the provided ones.
+func NewCleanupLifecycle(configFile string, lines []string, serviceName, imageURL string) (Lifecycle, error) {
+	var l Lifecycle
+	for i, line := range lines {
+		src := Source{Text: line, Description: fmt.Sprintf("cleanup command %d", i+1)}
+		if configFile != "" {
+			src.Description = filepath.Base(configFile) + " " + src.Description
+		}
+
+		argv := commandArgv(line, serviceName, imageURL, "")
+		a, err := argv(os.Getenv)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", src, err)
+		}
+
+		if len(a) == 0 {
+			continue
+		}
+
+		l = append(l, Command{Cmd: exec.Command(a[0], a[1:]...), Source: src, line: line, argv: argv})
+	}
+
+	return l, nil
+}
+
+// buildDefaultLifecycle builds a build and deploy command lifecycle with reasonable defaults for a non-Java
+// project. It uses `gcloud builds submit` for building the samples container image and submitting it to the container
+// and `gcloud run deploy` for deploying it to Cloud Run with the provided labels, if any.
+func buildDefaultLifecycle(serviceName, imageURL, labels string) Lifecycle {
+	a0 := append(util.GcloudCommonFlags, "builds", "submit", fmt.Sprintf("--tag=%s", imageURL))
+	a1 := append(util.GcloudCommonFlags, "run", "deploy", serviceName, fmt.Sprintf("--image=%s", imageURL),
+		"--platform=managed")
+	if labels != "" {
+		a1 = append(a1, "--update-labels="+labels)
+	}
+
+	src := Source{Description: defaultLifecycleDescription}
+	return Lifecycle{
+		{Cmd: exec.Command("gcloud", a0...), Source: src},
+		{Cmd: exec.Command("gcloud", a1...), Source: src},
+	}
+}
+
+// buildDefaultJavaLifecycle builds a build and deploy command lifecycle with reasonable defaults for Java
+// samples. It uses `com.google.cloud.tools:jib-maven-plugin:2.0.0:build` for building the samples container image and
+// submitting it to the container and `gcloud run deploy` for deploying it to Cloud Run.
+func buildDefaultJavaLifecycle(serviceName, imageURL, labels string) Lifecycle {
+	l := buildDefaultLifecycle(serviceName, imageURL, labels)
+
+	l[0].Cmd = exec.Command("mvn",
+		"compile",
+		"com.google.cloud.tools:jib-maven-plugin:2.0.0:build",
+		fmt.Sprintf("-Dimage=%s", imageURL),
+	)
+
+	for i := range l {
+		l[i].Source.Description = defaultJavaLifecycleDescription
+	}
+
+	return l
+}
+
+// buildDefaultAppEngineLifecycle builds a deploy command lifecycle with reasonable defaults for App Engine samples. It
+// uses `gcloud app deploy` for deploying the sample's app.yaml as a new version, named after the provided service name,
+// that isn't promoted to receive all traffic. App Engine builds the sample itself, so no container image is built.
+func buildDefaultAppEngineLifecycle(serviceName string) Lifecycle {
+	a := append(util.GcloudCommonFlags, "app", "deploy", "app.yaml")
+	a = append(a, appEngineVersionFlags(serviceName)...)
+
+	return Lifecycle{
+		{Cmd: exec.Command("gcloud", a...), Source: Source{Description: defaultAppEngineLifecycleDescription}},
+	}
+}
@@ -0,0 +1,398 @@
+package lifecycle
+
+import (
+	"bufio"
+	"context"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/config"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/docker"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type executeTest struct {
+	in       string   // input Markdown string
+	commands []string // expected command lines run by Lifecycle.Execute
+	dirs     []string // expected directories the commands are run in, relative to the initial directory
+	err      string   // expected string contained in return error of Lifecycle.Execute
+}
+
+var executeTests = []executeTest{
+	// export and unset
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"export TEST_GREETING=hello TEST_NAME='big world'\n" +
+			"echo ${TEST_GREETING} \"$TEST_NAME\"\n" +
+			"unset TEST_GREETING\n" +
+			"echo ${TEST_GREETING} $TEST_NAME\n" +
+			"```\n",
+		commands: []string{
+			"echo hello 'big world'",
+			"echo big world",
+		},
+		dirs: []string{".", "."},
+	},
+
+	// cd is carried across code blocks
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"cd sub\n" +
+			"echo one\n" +
+			"```\n" +
+			"[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"echo two\n" +
+			"cd ..\n" +
+			"echo three\n" +
+			"```\n",
+		commands: []string{
+			"echo one",
+			"echo two",
+			"echo three",
+		},
+		dirs: []string{"sub", "sub", "."},
+	},
+
+	// cd to a directory that doesn't exist
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"cd missing\n" +
+			"echo one\n" +
+			"```\n",
+		err: "cd: os.Stat",
+	},
+
+	// export of an invalid identifier
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"export 1=one\n" +
+			"```\n",
+		err: "not a valid identifier",
+	},
+}
+
+func TestExecute(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sst-lifecycle")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatalf("os.Mkdir: %v", err)
+	}
+
+	for i, tc := range executeTests {
+		l, err := extractLifecycle(bufio.NewScanner(strings.NewReader(tc.in)), "", "", "")
+		if err != nil {
+			t.Errorf("#%d: extractLifecycle: %v", i, err)
+			continue
+		}
+
+		e := &util.FakeExecutor{}
+		_, err = l.Execute(context.Background(), e, NewEnv(dir))
+
+		var errorMatch bool
+		if err == nil {
+			errorMatch = tc.err == ""
+		} else {
+			errorMatch = tc.err != "" && strings.Contains(err.Error(), tc.err)
+		}
+
+		if !errorMatch {
+			t.Errorf("#%d: error mismatch\nwant: %s\ngot: %v", i, tc.err, err)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		var dirs []string
+		for _, d := range e.Dirs() {
+			rel, _ := filepath.Rel(dir, d)
+			dirs = append(dirs, rel)
+		}
+
+		if !reflect.DeepEqual(e.Commands(), tc.commands) || !reflect.DeepEqual(dirs, tc.dirs) {
+			t.Errorf("#%d: result mismatch\nwant: %q in %q\ngot: %q in %q", i, tc.commands, tc.dirs, e.Commands(), dirs)
+		}
+	}
+}
+
+// pathExecutor is a util.Executor that records the path of the executable of every command it's asked to run.
+type pathExecutor struct {
+	paths []string
+}
+
+// Run implements util.Executor.
+func (e *pathExecutor) Run(ctx context.Context, cmd *exec.Cmd, dir string) (util.CommandResult, error) {
+	e.paths = append(e.paths, cmd.Path)
+	return util.CommandResult{}, nil
+}
+
+func TestExecuteExportedPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sst-lifecycle")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "bin"), 0755); err != nil {
+		t.Fatalf("os.Mkdir: %v", err)
+	}
+
+	want := filepath.Join(dir, "bin", "sst-hello")
+	if err := ioutil.WriteFile(want, []byte("#!/bin/sh\necho hello\n"), 0755); err != nil {
+		t.Fatalf("ioutil.WriteFile: %v", err)
+	}
+
+	in := "[//]: # ({sst-run-unix})\n" +
+		"```\n" +
+		"export PATH=$PATH:bin\n" +
+		"sst-hello\n" +
+		"```\n"
+	l, err := extractLifecycle(bufio.NewScanner(strings.NewReader(in)), "", "", "")
+	if err != nil {
+		t.Fatalf("extractLifecycle: %v", err)
+	}
+
+	e := &pathExecutor{}
+	if _, err := l.Execute(context.Background(), e, NewEnv(dir)); err != nil {
+		t.Fatalf("Lifecycle.Execute: %v", err)
+	}
+
+	if len(e.paths) != 1 || e.paths[0] != want {
+		t.Errorf("executable mismatch\nwant: [%s]\ngot: %q", want, e.paths)
+	}
+}
+
+type deploysTest struct {
+	in        string // input Markdown string
+	function  bool   // expected result of Lifecycle.DeploysCloudFunction
+	job       bool   // expected result of Lifecycle.DeploysCloudRunJob
+	appEngine bool   // expected result of Lifecycle.DeploysAppEngine
+}
+
+var deploysTests = []deploysTest{
+	// Cloud Run deploy
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"gcloud run deploy hello --image=gcr.io/project/hello\n" +
+			"```\n",
+	},
+
+	// Cloud Functions deploy
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"gcloud functions deploy hello_world \\\n" +
+			"  --runtime=go113 --trigger-http\n" +
+			"```\n",
+		function: true,
+	},
+
+	// Cloud Functions deploy in shell mode
+	{
+		in: "[//]: # ({sst-run-unix shell=bash})\n" +
+			"```\n" +
+			"cd hello\n" +
+			"gcloud functions deploy hello_world --trigger-http\n" +
+			"```\n",
+		function: true,
+	},
+
+	// other Cloud Functions command
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"gcloud functions describe hello_world\n" +
+			"```\n",
+	},
+
+	// Cloud Run job deploy
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"gcloud run jobs deploy hello --image=gcr.io/project/hello\n" +
+			"```\n",
+		job: true,
+	},
+
+	// App Engine deploy
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"gcloud app deploy app.yaml\n" +
+			"```\n",
+		appEngine: true,
+	},
+
+	// Cloud Run job create
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"gcloud run jobs create hello --image=gcr.io/project/hello --tasks=3\n" +
+			"```\n",
+		job: true,
+	},
+}
+
+func TestDeploys(t *testing.T) {
+	for i, tc := range deploysTests {
+		l, err := extractLifecycle(bufio.NewScanner(strings.NewReader(tc.in)), "", "", "")
+		if err != nil {
+			t.Errorf("#%d: extractLifecycle: %v", i, err)
+			continue
+		}
+
+		function, job, appEngine := l.DeploysCloudFunction(), l.DeploysCloudRunJob(), l.DeploysAppEngine()
+		if function != tc.function || job != tc.job || appEngine != tc.appEngine {
+			t.Errorf("#%d: result mismatch\nwant: function %t, job %t, App Engine %t\ngot: function %t, job %t, App Engine %t",
+				i, tc.function, tc.job, tc.appEngine, function, job, appEngine)
+		}
+	}
+}
+
+type addsLabelsTest struct {
+	in     string // input Markdown
+	labels bool   // expected result of AddsLabels
+}
+
+var addsLabelsTests = []addsLabelsTest{
+	// Cloud Run deploy
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"gcloud run deploy hello --image=gcr.io/project/hello\n" +
+			"```\n",
+		labels: true,
+	},
+
+	// deploy through a script
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"./deploy.sh hello\n" +
+			"```\n",
+	},
+}
+
+func TestAddsLabels(t *testing.T) {
+	const labels = "sst-run-id=1234,sst-created-at=1600000000"
+	for i, tc := range addsLabelsTests {
+		l, err := extractLifecycle(bufio.NewScanner(strings.NewReader(tc.in)), "", "", labels)
+		if err != nil {
+			t.Errorf("#%d: extractLifecycle: %v", i, err)
+			continue
+		}
+
+		if got := l.AddsLabels(labels); got != tc.labels {
+			t.Errorf("#%d: result mismatch\nwant: %t\ngot: %t", i, tc.labels, got)
+		}
+	}
+}
+
+func TestDefaultAppEngineLifecycle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sst-lifecycle")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, f := range []string{"app.yaml", "Dockerfile"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, f), nil, 0644); err != nil {
+			t.Fatalf("ioutil.WriteFile: %v", err)
+		}
+	}
+
+	l, err := NewLifecycle(dir, "hello", "gcr.io/project/hello", "", &config.Config{})
+	if err != nil {
+		t.Fatalf("NewLifecycle: %v", err)
+	}
+
+	if !l.DeploysAppEngine() {
+		t.Errorf("NewLifecycle: got lifecycle that doesn't deploy to App Engine: %v", l)
+	}
+
+	want := "gcloud --quiet app deploy app.yaml --no-promote --version=hello"
+	if len(l) != 1 || util.CommandLine(l[0].Cmd) != want {
+		t.Errorf("NewLifecycle: result mismatch\nwant: %s\ngot: %v", want, l)
+	}
+}
+
+func TestNewLocalLifecycle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sst-lifecycle")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := &docker.Container{Name: "hello-1234", Port: 5000}
+	want := []string{
+		"docker build --tag=sst-local/hello .",
+		"docker run --detach --name=hello-1234 --env=PORT=8080 --publish=127.0.0.1:5000:8080 sst-local/hello",
+	}
+	if got := commandLines(NewLocalLifecycle(dir, "sst-local/hello", c)); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewLocalLifecycle: result mismatch\nwant: %q\ngot: %q", want, got)
+	}
+
+	// Java samples without a Dockerfile are built with Jib.
+	if err := ioutil.WriteFile(filepath.Join(dir, "pom.xml"), nil, 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile: %v", err)
+	}
+
+	want[0] = "mvn compile com.google.cloud.tools:jib-maven-plugin:2.0.0:dockerBuild -Dimage=sst-local/hello"
+	if got := commandLines(NewLocalLifecycle(dir, "sst-local/hello", c)); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewLocalLifecycle: java result mismatch\nwant: %q\ngot: %q", want, got)
+	}
+}
+
+func TestNewCleanupLifecycle(t *testing.T) {
+	lines := []string{
+		"gcloud sql instances delete hello-db",
+		"gcloud run services delete hello --region=us-east1",
+		"",
+	}
+
+	l, err := NewCleanupLifecycle("config.yaml", lines, "hello-1234", "gcr.io/project/hello")
+	if err != nil {
+		t.Fatalf("NewCleanupLifecycle: %v", err)
+	}
+
+	want := []string{
+		"gcloud --quiet sql instances delete hello-db",
+		"gcloud --quiet run services delete hello-1234 --region=us-east1",
+	}
+	if got := commandLines(l); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewCleanupLifecycle: result mismatch\nwant: %q\ngot: %q", want, got)
+	}
+
+	if got, want := l[1].Source.String(), "config.yaml cleanup command 2"; got != want {
+		t.Errorf("NewCleanupLifecycle: source mismatch\nwant: %s\ngot: %s", want, got)
+	}
+
+	if _, err := NewCleanupLifecycle("config.yaml", []string{"echo 'unterminated"}, "", ""); err == nil {
+		t.Errorf("NewCleanupLifecycle: got nil error for unterminated quote")
+	}
+}
+
+// commandLines returns the command lines of the commands of the provided Lifecycle.
+func commandLines(l Lifecycle) []string {
+	var lines []string
+	for _, c := range l {
+		lines = append(lines, util.CommandLine(c.Cmd))
+	}
+
+	return lines
+}
@@ -0,0 +1,212 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// The service name and container image URL Lint parses commands with. Image URLs that are left untouched in the parsed
+// commands aren't replaced when the sample is tested.
+const (
+	lintServiceName = "sst-lint-service"
+	lintImageURL    = "gcr.io/sst-lint/image"
+)
+
+// imageFlags are the flags whose values are expected to be container image URLs.
+var imageFlags = []string{"--image", "--tag", "-Dimage"}
+
+// Severity is the severity of a Diagnostic.
+type Severity string
+
+const (
+	// SeverityError is the Severity of problems that make the tool fail or test the wrong thing.
+	SeverityError Severity = "error"
+
+	// SeverityWarning is the Severity of problems that may make the tool fail, depending on the environment it's run in.
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a problem Lint found in a README.
+type Diagnostic struct {
+	File     string
+	Line     int
+	Severity Severity
+	Message  string
+}
+
+// String formats the Diagnostic as file:line: severity: message.
+func (d Diagnostic) String() string {
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: %s: %s", d.File, d.Severity, d.Message)
+	}
+
+	return fmt.Sprintf("%s:%d: %s: %s", d.File, d.Line, d.Severity, d.Message)
+}
+
+// Lint parses the README file with the given name the same way parseREADME does and reports the problems found in it
+// without executing any commands.
+func Lint(filename string) ([]Diagnostic, error) {
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return []Diagnostic{{File: filename, Severity: SeverityWarning, Message: "README not found; the default lifecycle will be used"}}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("os.Open: %w", err)
+	}
+	defer file.Close()
+
+	ds := lintLifecycle(bufio.NewScanner(file))
+	for i := range ds {
+		ds[i].File = filename
+	}
+
+	return ds, nil
+}
+
+// lintLifecycle is a helper function for Lint. It takes a scanner that reads from a Markdown file and reports the
+// problems found in its code blocks annotated by the codeTag.
+func lintLifecycle(scanner *bufio.Scanner) []Diagnostic {
+	codeBlocks, err := extractCodeBlocks(scanner)
+	if err != nil {
+		// The structure of the file can't be trusted past the first structural problem.
+		return []Diagnostic{lineErrorDiagnostic(err)}
+	}
+
+	if len(codeBlocks) == 0 {
+		return []Diagnostic{{
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("no code blocks immediately preceded by %s found; the default lifecycle will be used", codeTag),
+		}}
+	}
+
+	l := &linter{env: NewEnv(""), undefined: make(map[string]bool)}
+	parseEnv := NewEnv("")
+	for _, b := range codeBlocks {
+		cmds, err := b.toCommands(lintServiceName, lintImageURL, "", parseEnv)
+		if err != nil {
+			l.diagnostics = append(l.diagnostics, lineErrorDiagnostic(err))
+			continue
+		}
+
+		for _, c := range cmds {
+			// Code blocks in shell mode are run as written.
+			if c.line != "" {
+				l.lintCommand(c)
+			}
+		}
+	}
+
+	return l.diagnostics
+}
+
+// lineErrorDiagnostic converts an error returned while parsing a README to a Diagnostic, citing the line it was caused
+// by, if known.
+func lineErrorDiagnostic(err error) Diagnostic {
+	d := Diagnostic{Severity: SeverityError, Message: err.Error()}
+
+	var le *lineError
+	if errors.As(err, &le) {
+		d.Line = le.line
+		d.Message = le.err.Error()
+
+		// Leave out the code block dump, the line number is enough.
+		if errors.Is(le.err, errCodeBlockEndAfterLineCont) {
+			d.Message = errCodeBlockEndAfterLineCont.Error()
+		}
+	}
+
+	return d
+}
+
+// linter holds the state of Lint across the commands of a README.
+type linter struct {
+	diagnostics []Diagnostic
+
+	// The environment the commands are expanded against, as modified by the export and unset built-ins.
+	env *Env
+
+	// The environment variables that were already reported as undefined.
+	undefined map[string]bool
+}
+
+// report adds a Diagnostic for the provided Command.
+func (l *linter) report(c Command, s Severity, format string, a ...interface{}) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{Line: c.Source.Line, Severity: s, Message: fmt.Sprintf(format, a...)})
+}
+
+// lintCommand reports the problems found in a single Command parsed from a README.
+func (l *linter) lintCommand(c Command) {
+	words, err := splitWords(c.line, func(name string) string {
+		if v, ok := l.env.LookupEnv(name); ok {
+			return v
+		}
+
+		if !l.undefined[name] {
+			l.undefined[name] = true
+			l.report(c, SeverityWarning, "environment variable $%s is not set", name)
+		}
+
+		// Keep the reference, so that image URLs containing it can still be checked.
+		return "${" + name + "}"
+	})
+	if err != nil || len(words) == 0 {
+		return
+	}
+
+	switch words[0] {
+	case "export", "unset":
+		if _, err := l.env.builtin(words); err != nil {
+			l.report(c, SeverityError, "%v", err)
+		}
+		return
+	}
+
+	if isGcloudCommand(words, "run") {
+		for _, w := range words {
+			if w == "--region" || strings.HasPrefix(w, "--region=") {
+				l.report(c, SeverityError, "--region flag found; set the Cloud Run region through the run/region gcloud property instead")
+			}
+		}
+	}
+
+	if i, fallback := serviceNameIndex(words, l.env.Getenv); fallback {
+		l.report(c, SeverityWarning, "service name couldn't be detected, assuming it's %q; set "+
+			"$CLOUD_RUN_SERVICE_NAME to the service name used in the README", words[i])
+	}
+
+	for i, w := range words {
+		for _, f := range imageFlags {
+			var url string
+			if w == f && i+1 < len(words) {
+				url = words[i+1]
+			} else if strings.HasPrefix(w, f+"=") {
+				url = strings.TrimPrefix(w, f+"=")
+			} else {
+				continue
+			}
+
+			if !imageURLRegexp.MatchString(url) {
+				l.report(c, SeverityError, "container image URL %q isn't a Container Registry or Artifact Registry "+
+					"image URL and won't be replaced", url)
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package lifecycle
+
+import (
+	"bufio"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type lintLifecycleTest struct {
+	in          string   // input Markdown string
+	diagnostics []string // expected diagnostics of lintLifecycle, formatted by Diagnostic.String without a file
+}
+
+var lintLifecycleTests = []lintLifecycleTest{
+	// no problems
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"export PROJECT=hello\n" +
+			"gcloud builds submit --tag=gcr.io/${PROJECT}/world\n" +
+			"gcloud run deploy hello_world --image gcr.io/${PROJECT}/world\n" +
+			"```\n",
+	},
+
+	// no code blocks
+	{
+		in: "```\n" +
+			"echo hello world\n" +
+			"```\n",
+		diagnostics: []string{
+			": warning: no code blocks immediately preceded by {sst-run-unix} found; the default lifecycle will be used",
+		},
+	},
+
+	// tagged block without a fence
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"echo hello world\n",
+		diagnostics: []string{
+			":2: error: " + errCodeBlockStartNotFound.Error(),
+		},
+	},
+
+	// unclosed fence
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"echo hello world\n",
+		diagnostics: []string{
+			":2: error: " + errCodeBlockNotClosed.Error(),
+		},
+	},
+
+	// trailing line continuation and unterminated quote, one per code block
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"echo hello \\\n" +
+			"```\n" +
+			"[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"echo 'hello\n" +
+			"```\n",
+		diagnostics: []string{
+			":3: error: " + errCodeBlockEndAfterLineCont.Error(),
+			":7: error: " + errUnterminatedSingleQuote.Error(),
+		},
+	},
+
+	// --region flag and service name detected by the failsafe
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"gcloud run services describe hello_world --region=us-central1\n" +
+			"```\n",
+		diagnostics: []string{
+			":3: error: --region flag found; set the Cloud Run region through the run/region gcloud property instead",
+			":3: warning: service name couldn't be detected, assuming it's \"hello_world\"; set " +
+				"$CLOUD_RUN_SERVICE_NAME to the service name used in the README",
+		},
+	},
+
+	// undefined environment variables are reported once, and unset variables are undefined
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"export GREETING=hello\n" +
+			"echo $GREETING $SST_LINT_UNDEFINED\n" +
+			"unset GREETING\n" +
+			"echo $GREETING $SST_LINT_UNDEFINED\n" +
+			"```\n",
+		diagnostics: []string{
+			":4: warning: environment variable $SST_LINT_UNDEFINED is not set",
+			":6: warning: environment variable $GREETING is not set",
+		},
+	},
+
+	// image URLs that won't be replaced
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"gcloud builds submit --tag docker.io/project/image\n" +
+			"gcloud run deploy hello_world --image=us-central1-docker.pkg.dev/project/repo/image\n" +
+			"mvn compile jib:build -Dimage=eu.gcr.io/project/image\n" +
+			"```\n",
+		diagnostics: []string{
+			":3: error: container image URL \"docker.io/project/image\" isn't a Container Registry or Artifact Registry " +
+				"image URL and won't be replaced",
+		},
+	},
+
+	// shell mode code blocks are run as written
+	{
+		in: "[//]: # ({sst-run-unix shell=bash})\n" +
+			"```\n" +
+			"echo $SST_LINT_UNDEFINED | tee out.txt\n" +
+			"```\n",
+	},
+}
+
+func TestLintLifecycle(t *testing.T) {
+	if err := os.Unsetenv("SST_LINT_UNDEFINED"); err != nil {
+		t.Fatalf("os.Unsetenv: %v", err)
+	}
+
+	for i, tc := range lintLifecycleTests {
+		s := bufio.NewScanner(strings.NewReader(tc.in))
+
+		var ds []string
+		for _, d := range lintLifecycle(s) {
+			ds = append(ds, d.String())
+		}
+
+		if !reflect.DeepEqual(ds, tc.diagnostics) {
+			t.Errorf("#%d: result mismatch\nwant: %q\ngot: %q", i, tc.diagnostics, ds)
+		}
+	}
+}
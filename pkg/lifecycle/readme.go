@@ -0,0 +1,684 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+const (
+	// The tag that should appear immediately before code blocks in a README to indicate that the enclosed commands
+	// are to be used by this program for building and deploying the sample. Options can be added to the tag, e.g.
+	// {sst-run-unix shell=bash}.
+	codeTag = "{sst-run-unix}"
+
+	// The environment variables the Cloud Run service name and container image URL are exported as to code blocks
+	// that are run through a shell.
+	serviceNameEnvVar = "SST_SERVICE_NAME"
+	imageURLEnvVar    = "SST_IMAGE_URL"
+
+	// A non-quoted backslash in bash at the end of a line indicates a line continuation from the current line to the
+	// next line.
+	bashLineContChar = '\\'
+)
+
+var (
+	// imageURLRegexp matches Container Registry (e.g. gcr.io/project/image or eu.gcr.io/project/image) and Artifact
+	// Registry (e.g. us-central1-docker.pkg.dev/project/repository/image) container image URLs. Only the characters of
+	// image references and environment variable expansions are matched, so that the quotes and shell operators
+	// surrounding a URL in a shell script are left in place.
+	imageURLRegexp = regexp.MustCompile(`(?:[a-z]+\.)?gcr\.io/[\w.:@${}/-]+/[\w.:@${}-]+|` +
+		`[a-z0-9-]+-docker\.pkg\.dev/[\w.:@${}/-]+/[\w.:@${}/-]+/[\w.:@${}-]+`)
+
+	codeTagRegexp = regexp.MustCompile(`\{sst-run-unix((?:\s+[^\s{}]+)*)\s*\}`)
+	wordRegexp    = regexp.MustCompile(`\S+`)
+
+	// shellOperatorRegexp matches the words of a shell script that end a command or redirect its input or output, e.g.
+	// |, &&, ;, > or 2>&1.
+	shellOperatorRegexp = regexp.MustCompile(`^(?:\|\|?|&&?|;|\d*[<>].*|&>.*)$`)
+
+	// shellFlags maps the shells code blocks can be run through to the flags they're run with, so that the script
+	// fails as soon as any of its commands fail.
+	shellFlags = map[string][]string{
+		"bash": {"-euo", "pipefail"},
+	}
+
+	mdCodeFenceStartRegexp = regexp.MustCompile("^\\w*`{3,}[^`]*$")
+
+	errNoReadmeCodeBlocksFound   = fmt.Errorf("lifecycle.extractCodeBlocks: no code blocks immediately preceded by %s found", codeTag)
+	errCodeBlockNotClosed        = fmt.Errorf("unexpected EOF: code block not closed")
+	errCodeBlockStartNotFound    = fmt.Errorf("expecting start of code block immediately after code tag")
+	errEOFAfterCodeTag           = fmt.Errorf("unexpected EOF: file ended immediately after code tag")
+	errCodeBlockEndAfterLineCont = fmt.Errorf("end of code block: expecting command line continuation")
+	errUnknownCodeTagOption      = fmt.Errorf("unknown code tag option")
+	errUnsupportedShell          = fmt.Errorf("unsupported shell")
+)
+
+// lineError is an error caused by a specific line of a README.
+type lineError struct {
+	line int
+	err  error
+}
+
+func (e *lineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.line, e.err)
+}
+
+func (e *lineError) Unwrap() error {
+	return e.err
+}
+
+// codeBlock holds the lines of a code block containing terminal commands. codeBlocks, for example, could be used to
+// hold the terminal commands inside of a Markdown code block.
+type codeBlock struct {
+	lines []string
+
+	// The line number of the first of the lines in the file the code block was extracted from.
+	startLine int
+
+	// The index of the code block among the code blocks annotated by the code tag in the file it was extracted from.
+	index int
+
+	// The shell the code block should be run through as a single script, if any. If empty, each command is executed
+	// directly.
+	shell string
+}
+
+// toCommands extracts the terminal commands contained within the current codeBlock. It handles the expansion of
+// environment variables, line continuations, and shell quoting (see splitWords). Environment variables are expanded
+// against the tester's environment in the returned exec.Cmds, and again against the Lifecycle's Env when the commands
+// are executed. It also detects Cloud Run service names and Container Registry or Artifact Registry container image
+// URLs and replaces them with the ones provided, and adds the provided labels, formatted as a gcloud --labels flag
+// value, to the commands that deploy Cloud Run services or jobs or Cloud Functions (see addLabels). The tester's
+// environment is the provided Env, which is carried across code blocks: the export and unset built-ins in the
+// codeBlock are applied to it, so that they're taken into account by the commands that follow them. Each Command's
+// Source holds the line number the command starts at, which is also included in any returned error.
+//
+// If the codeBlock has a shell, a single Command running the whole code block as a script through that shell is
+// returned instead (see toScriptCommand).
+func (cb codeBlock) toCommands(serviceName, imageURL, labels string, env *Env) ([]Command, error) {
+	var cmds []Command
+	var script []string
+
+	for i := 0; i < len(cb.lines); i++ {
+		line := cb.lines[i]
+		if line == "" {
+			continue
+		}
+		start := i
+
+		// If there is a backslash at the end of the line, this is a multiline command. Keep scanning to get entire
+		// command.
+		for line[len(line)-1] == bashLineContChar {
+			line = line[:len(line)-1]
+
+			i++
+			if i >= len(cb.lines) {
+				return nil, &lineError{
+					line: cb.startLine + start,
+					err:  fmt.Errorf("%w; code block dump:\n%s", errCodeBlockEndAfterLineCont, strings.Join(cb.lines, "\n")),
+				}
+			}
+
+			l := cb.lines[i]
+			if l == "" {
+				break
+			}
+
+			line = line + l
+		}
+		src := cb.source(start, i)
+
+		if cb.shell != "" {
+			line = replaceScriptServiceName(replaceScriptImageURL(line, imageURL), serviceName, env.Getenv)
+			line = replaceScriptLabels(line, labels)
+			script = append(script, replaceScriptAppEngineVersion(line, serviceName))
+			continue
+		}
+
+		argv := commandArgv(line, serviceName, imageURL, labels)
+		a, err := argv(env.Getenv)
+		if err != nil {
+			return nil, &lineError{line: src.Line, err: err}
+		}
+
+		if len(a) == 0 {
+			continue
+		}
+
+		// Only the built-ins that modify environment variables are applied, since the working directory the commands
+		// are executed in isn't known yet. Their errors are returned once the commands are executed.
+		if a[0] == "export" || a[0] == "unset" {
+			env.builtin(a)
+		}
+
+		cmds = append(cmds, Command{Cmd: exec.Command(a[0], a[1:]...), Source: src, line: line, argv: argv})
+	}
+
+	if cb.shell != "" {
+		return []Command{cb.toScriptCommand(script, serviceName, imageURL)}, nil
+	}
+
+	return cmds, nil
+}
+
+// commandArgv returns a function that splits a README command line into the arguments of the command to execute,
+// expanding environment variables with the provided mapping. It replaces the Cloud Run service name and Container
+// Registry container image URLs in the command with the provided ones, adds the provided labels to commands that deploy
+// resources (see addLabels), and adds util.GcloudCommonFlags to gcloud commands.
+func commandArgv(line, serviceName, imageURL, labels string) func(mapping func(string) string) ([]string, error) {
+	return func(mapping func(string) string) ([]string, error) {
+		sp, err := splitWords(line, mapping)
+		if err != nil || len(sp) == 0 {
+			return nil, err
+		}
+
+		for j := range sp {
+			sp[j] = imageURLRegexp.ReplaceAllString(sp[j], imageURL)
+		}
+		sp = replaceServiceName(sp, serviceName, mapping)
+		sp = replaceAppEngineVersion(sp, serviceName)
+		sp = addLabels(sp, labels)
+
+		if sp[0] == "gcloud" {
+			a := append([]string{"gcloud"}, util.GcloudCommonFlags...)
+			return append(a, sp[1:]...), nil
+		}
+
+		return sp, nil
+	}
+}
+
+// toScriptCommand returns a Command that runs the provided lines of the codeBlock as a single script through the
+// codeBlock's shell. The script fails as soon as any of its commands fail, and the Cloud Run service name and container
+// image URL are exported to it as $SST_SERVICE_NAME and $SST_IMAGE_URL, in addition to the Lifecycle's Env. Since
+// util.GcloudCommonFlags can't be added to the gcloud commands of a script, gcloud prompts are disabled with
+// $CLOUDSDK_CORE_DISABLE_PROMPTS instead.
+func (cb codeBlock) toScriptCommand(script []string, serviceName, imageURL string) Command {
+	a := append(append([]string(nil), shellFlags[cb.shell]...), "-c", strings.Join(script, "\n"))
+	cmd := exec.Command(cb.shell, a...)
+	cmd.Env = append(cmd.Env,
+		"CLOUDSDK_CORE_DISABLE_PROMPTS=1",
+		fmt.Sprintf("%s=%s", serviceNameEnvVar, serviceName),
+		fmt.Sprintf("%s=%s", imageURLEnvVar, imageURL),
+	)
+
+	return Command{Cmd: cmd, Source: cb.source(0, len(cb.lines)-1)}
+}
+
+// source returns the Source of the command spanning the lines of the codeBlock from index start to index end,
+// inclusive.
+func (cb codeBlock) source(start, end int) Source {
+	return Source{
+		Block:   cb.index,
+		Line:    cb.startLine + start,
+		EndLine: cb.startLine + end,
+		Text:    strings.TrimSpace(strings.Join(cb.lines[start:end+1], "\n")),
+	}
+}
+
+// parseCodeTagOptions parses the options of a code tag, e.g. shell=bash in {sst-run-unix shell=bash}, into the
+// provided codeBlock.
+func parseCodeTagOptions(options string, block *codeBlock) error {
+	for _, o := range strings.Fields(options) {
+		sp := strings.SplitN(o, "=", 2)
+		if len(sp) != 2 || sp[0] != "shell" {
+			return fmt.Errorf("%w: %s", errUnknownCodeTagOption, o)
+		}
+
+		if _, ok := shellFlags[sp[1]]; !ok {
+			return fmt.Errorf("%w: %s", errUnsupportedShell, sp[1])
+		}
+
+		block.shell = sp[1]
+	}
+
+	return nil
+}
+
+// parseREADME parses a README file with the given name. It parses terminal commands in code blocks annotated by the
+// codeTag and loads them into a Lifecycle. In the process, it replaces the Cloud Run service name and container image
+// URL with the provided inputs, and adds the provided labels to the resources deployed. It also expands environment
+// variables and supports bash-style line continuations.
+func parseREADME(filename, serviceName, imageURL, labels string) (Lifecycle, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	l, err := extractLifecycle(scanner, serviceName, imageURL, labels)
+	for i := range l {
+		l[i].Source.File = filename
+	}
+
+	return l, err
+}
+
+// HasCodeTags reports whether the README file with the given name contains any codeTag, i.e. whether build and deploy
+// commands would be parsed from it.
+func HasCodeTags(filename string) (bool, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return false, fmt.Errorf("os.Open: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if codeTagRegexp.MatchString(scanner.Text()) {
+			return true, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("bufio.Scanner.Scan: %w", err)
+	}
+
+	return false, nil
+}
+
+// extractLifecycle is a helper function for parseREADME. It takes a scanner that reads from a Markdown file and parses
+// terminal commands in code blocks annotated by the codeTag and loads them into a Lifecycle. In the process, it
+// replaces the Cloud Run service name and container image URL with the provided inputs, and adds the provided labels to
+// the resources deployed. It also expands environment variables and supports bash-style line continuations.
+func extractLifecycle(scanner *bufio.Scanner, serviceName, imageURL, labels string) (Lifecycle, error) {
+	codeBlocks, err := extractCodeBlocks(scanner)
+	if err != nil {
+		return nil, fmt.Errorf("lifecycle.extractCodeBlocks: %w", err)
+	}
+
+	if len(codeBlocks) == 0 {
+		return nil, errNoReadmeCodeBlocksFound
+	}
+
+	var l Lifecycle
+	env := NewEnv("")
+	for _, b := range codeBlocks {
+		cmds, err := b.toCommands(serviceName, imageURL, labels, env)
+		if err != nil {
+			return l, fmt.Errorf("codeBlock.toCommands: %w", err)
+		}
+
+		l = append(l, cmds...)
+	}
+
+	return l, nil
+}
+
+// codeBlocks extracts code blocks out of a bufio.Scanner that's reading from a Markdown file immediately prefaced with
+// a line containing codeTag. It returns an 2d slice of code blocks, each containing an array of lines contained within
+// that code block.
+func extractCodeBlocks(scanner *bufio.Scanner) ([]codeBlock, error) {
+	var blocks []codeBlock
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+
+		if m := codeTagRegexp.FindStringSubmatch(line); m != nil {
+			block := codeBlock{index: len(blocks)}
+			if err := parseCodeTagOptions(m[1], &block); err != nil {
+				return nil, &lineError{line: lineNum, err: err}
+			}
+
+			if s := scanner.Scan(); !s {
+				if err := scanner.Err(); err != nil {
+					return nil, &lineError{line: lineNum, err: fmt.Errorf("bufio.Scanner.Scan: %w", err)}
+				}
+				return nil, &lineError{line: lineNum, err: errEOFAfterCodeTag}
+			}
+			lineNum++
+
+			startCodeBlockLine := scanner.Text()
+			m := mdCodeFenceStartRegexp.MatchString(startCodeBlockLine)
+			if !m {
+				return nil, &lineError{line: lineNum, err: errCodeBlockStartNotFound}
+			}
+
+			c := strings.Count(startCodeBlockLine, "`")
+			mdCodeFenceEndRegexp := regexp.MustCompile(fmt.Sprintf("^\\w*`{%d,}\\w*$", c))
+
+			block.startLine = lineNum + 1
+			var blockClosed bool
+			for scanner.Scan() {
+				lineNum++
+				line = strings.TrimSpace(scanner.Text())
+				if mdCodeFenceEndRegexp.MatchString(line) {
+					blockClosed = true
+					break
+				}
+
+				block.lines = append(block.lines, line)
+			}
+
+			if err := scanner.Err(); err != nil {
+				return nil, &lineError{line: lineNum, err: fmt.Errorf("bufio.Scanner.Scan: %w", err)}
+			}
+
+			if !blockClosed {
+				return nil, &lineError{line: block.startLine - 1, err: errCodeBlockNotClosed}
+			}
+
+			blocks = append(blocks, block)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, &lineError{line: lineNum, err: fmt.Errorf("bufio.Scanner.Scan: %w", err)}
+	}
+
+	return blocks, nil
+}
+
+// replaceServiceName takes the words of a terminal command as input and replaces the Cloud Run service or job or Cloud
+// Function name, if any. If the user specified the service name in $CLOUD_RUN_SERVICE_NAME, as looked up with the
+// provided mapping, it replaces that.
+// Otherwise, it detects whether the command is a gcloud run or gcloud functions command and replaces the argument
+// following deploy or update (or create or execute for Cloud Run jobs), or as a failsafe, the last argument that
+// isn't a flag with the input service name.
+func replaceServiceName(words []string, serviceName string, mapping func(string) string) []string {
+	if i, _ := serviceNameIndex(words, mapping); i >= 0 {
+		words[i] = serviceName
+	}
+
+	return words
+}
+
+// serviceNameIndex returns the index of the Cloud Run service or Cloud Function name in the words of a terminal
+// command, or -1 if the command isn't a gcloud run or gcloud functions command. See replaceServiceName for how the
+// service name is detected. It also reports whether the service name was detected by the failsafe, which may not
+// always be accurate.
+func serviceNameIndex(words []string, mapping func(string) string) (int, bool) {
+	if !isGcloudCommand(words, "run") && !isGcloudCommand(words, "functions") {
+		return -1, false
+	}
+	words = commandWords(words)
+
+	// Detects if the user specified the Cloud Run service name in an environment variable
+	if n := mapping("CLOUD_RUN_SERVICE_NAME"); n != "" {
+		for i := 0; i < len(words); i++ {
+			if words[i] == n {
+				return i, false
+			}
+		}
+	}
+
+	// Searches for specific gcloud keywords and takes service name from them
+	job := isGcloudCommand(words, "jobs")
+	for i := 0; i < len(words)-1; i++ {
+		if words[i] == "deploy" || words[i] == "update" || job && (words[i] == "create" || words[i] == "execute") {
+			return i + 1, false
+		}
+	}
+
+	// Provides a failsafe if neither of the above options work
+	for i := len(words) - 1; i >= 0; i-- {
+		if !strings.Contains(words[i], "--") {
+			return i, true
+		}
+	}
+
+	return -1, false
+}
+
+// commandWords returns the words of a terminal command up to the first shell operator, e.g. | or >, so that the words
+// of commands piped to or redirected from it in a shell script aren't taken for its arguments.
+func commandWords(words []string) []string {
+	for i, w := range words {
+		if shellOperatorRegexp.MatchString(w) {
+			return words[:i]
+		}
+	}
+
+	return words
+}
+
+// replaceAppEngineVersion takes the words of a terminal command as input and, if it's a gcloud app deploy command,
+// makes it deploy a version named after the provided service name that isn't promoted to receive all traffic. Any
+// --version or --promote flags in the command are removed.
+func replaceAppEngineVersion(words []string, serviceName string) []string {
+	if !isAppEngineDeployCommand(words) {
+		return words
+	}
+
+	var out []string
+	inserted := false
+	for i := 0; i < len(words); i++ {
+		switch w := words[i]; {
+		case w == "--version" || w == "-v":
+			i++
+		case strings.HasPrefix(w, "--version=") || w == "--promote" || w == "--no-promote":
+		default:
+			out = append(out, w)
+			if w == "deploy" && !inserted {
+				out = append(out, appEngineVersionFlags(serviceName)...)
+				inserted = true
+			}
+		}
+	}
+
+	return out
+}
+
+// appEngineVersionFlags returns the flags replaceAppEngineVersion adds to gcloud app deploy commands.
+func appEngineVersionFlags(serviceName string) []string {
+	return []string{"--no-promote", "--version=" + serviceName}
+}
+
+// isAppEngineDeployCommand reports whether the words of a terminal command make up a gcloud app deploy command.
+func isAppEngineDeployCommand(words []string) bool {
+	if !isGcloudCommand(words, "app") {
+		return false
+	}
+
+	for _, w := range words {
+		if w == "deploy" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// replaceScriptAppEngineVersion is the equivalent of replaceAppEngineVersion for a line of a shell script. The line is
+// split into words without expanding any environment variables, and is returned unchanged if it can't be split or
+// isn't a gcloud app deploy command.
+func replaceScriptAppEngineVersion(line, serviceName string) string {
+	words, err := splitWords(line, nil)
+	if err != nil || !isAppEngineDeployCommand(words) {
+		return line
+	}
+
+	var b strings.Builder
+	prev, skipNext, inserted := 0, false, false
+	for _, loc := range wordRegexp.FindAllStringIndex(line, -1) {
+		w := line[loc[0]:loc[1]]
+
+		drop := skipNext
+		skipNext = false
+		switch {
+		case w == "--version" || w == "-v":
+			drop, skipNext = true, true
+		case strings.HasPrefix(w, "--version=") || w == "--promote" || w == "--no-promote":
+			drop = true
+		}
+
+		// Dropped words are removed along with the whitespace preceding them.
+		if !drop {
+			b.WriteString(line[prev:loc[1]])
+		}
+		prev = loc[1]
+
+		if w == "deploy" && !drop && !inserted {
+			b.WriteString(" " + strings.Join(appEngineVersionFlags(serviceName), " "))
+			inserted = true
+		}
+	}
+	b.WriteString(line[prev:])
+
+	return b.String()
+}
+
+// replaceScriptServiceName replaces the Cloud Run service name, if any, in a line of a shell script. The service name
+// is detected the same way as in replaceServiceName, without expanding any environment variables in the line, and its
+// first unquoted occurrence in the line is replaced. Lines that can't be split into words are returned unchanged.
+func replaceScriptServiceName(line, serviceName string, mapping func(string) string) string {
+	words, err := splitWords(line, nil)
+	if err != nil {
+		return line
+	}
+
+	i, _ := serviceNameIndex(words, mapping)
+	if i < 0 {
+		return line
+	}
+
+	for _, loc := range wordRegexp.FindAllStringIndex(line, -1) {
+		if line[loc[0]:loc[1]] == words[i] {
+			return line[:loc[0]] + serviceName + line[loc[1]:]
+		}
+	}
+
+	return line
+}
+
+// labelFlags are the gcloud flags that set the labels of the resource deployed by a command. Labels added to a command
+// that already has one of them are merged into its value.
+var labelFlags = []string{"--labels", "--update-labels"}
+
+// addLabels takes the words of a terminal command as input and, if it's a gcloud command that deploys a Cloud Run
+// service or job or a Cloud Function, adds the provided labels to the resource it deploys. They're merged into the
+// command's --labels or --update-labels flag, if it has one, and added with labelsFlag otherwise. If labels is empty,
+// words are returned unchanged.
+func addLabels(words []string, labels string) []string {
+	flag := labelsFlag(words)
+	if labels == "" || flag == "" {
+		return words
+	}
+
+	cmd := commandWords(words)
+	for i, w := range cmd {
+		for _, f := range labelFlags {
+			if w == f && i+1 < len(cmd) {
+				words[i+1] += "," + labels
+				return words
+			}
+
+			if strings.HasPrefix(w, f+"=") {
+				words[i] += "," + labels
+				return words
+			}
+		}
+	}
+
+	out := append(append([]string(nil), cmd...), flag+"="+labels)
+	return append(out, words[len(cmd):]...)
+}
+
+// labelsFlag returns the gcloud flag that adds labels to the resource deployed by the words of a terminal command, or
+// an empty string if the command doesn't deploy a Cloud Run service or job or a Cloud Function. gcloud run jobs create
+// only supports --labels, which can't be combined with --update-labels in the other deploy commands.
+func labelsFlag(words []string) string {
+	words = commandWords(words)
+	if !isGcloudCommand(words, "run") && !isGcloudCommand(words, "functions") {
+		return ""
+	}
+
+	jobs := isGcloudCommand(words, "jobs")
+	for _, w := range words {
+		switch {
+		case w == "deploy":
+			return "--update-labels"
+		case w == "create" && jobs:
+			return "--labels"
+		}
+	}
+
+	return ""
+}
+
+// replaceScriptLabels is the equivalent of addLabels for a line of a shell script. The line is split into words
+// without expanding any environment variables, and is returned unchanged if it can't be split or doesn't deploy a
+// Cloud Run service or job or a Cloud Function. Labels added to an existing flag are appended to its value as written,
+// quotes included.
+func replaceScriptLabels(line, labels string) string {
+	words, err := splitWords(line, nil)
+	if err != nil || labels == "" {
+		return line
+	}
+
+	flag := labelsFlag(words)
+	if flag == "" {
+		return line
+	}
+
+	// The labels are added after the last word of the command, before any shell operator.
+	end := len(line)
+	locs := wordRegexp.FindAllStringIndex(line, -1)
+	for i, loc := range locs {
+		w := line[loc[0]:loc[1]]
+		if shellOperatorRegexp.MatchString(w) {
+			end = locs[i-1][1]
+			break
+		}
+
+		for _, f := range labelFlags {
+			if w == f && i+1 < len(locs) {
+				end = locs[i+1][1]
+				return line[:end] + "," + labels + line[end:]
+			}
+
+			if strings.HasPrefix(w, f+"=") {
+				return line[:loc[1]] + "," + labels + line[loc[1]:]
+			}
+		}
+	}
+
+	return line[:end] + " " + flag + "=" + labels + line[end:]
+}
+
+// replaceScriptImageURL replaces the Container Registry and Artifact Registry container image URLs in a line of a shell
+// script with the provided one.
+func replaceScriptImageURL(line, imageURL string) string {
+	return wordRegexp.ReplaceAllStringFunc(line, func(w string) string {
+		return imageURLRegexp.ReplaceAllString(w, imageURL)
+	})
+}
+
+// isGcloudCommand reports whether the words of a terminal command make up a gcloud command of the provided command
+// group, e.g. run for gcloud run commands.
+func isGcloudCommand(words []string, group string) bool {
+	if len(words) == 0 || words[0] != "gcloud" {
+		return false
+	}
+
+	for _, w := range words[1:] {
+		if w == group {
+			return true
+		}
+	}
+
+	return false
+}
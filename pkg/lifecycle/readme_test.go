@@ -0,0 +1,794 @@
+package lifecycle
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setEnv takes a map of environment variables to their values and sets the program's environment accordingly.
+func setEnv(e map[string]string) error {
+	for k, v := range e {
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unsetEnv takes a map of environment variables to their values and unsets the environment variables in the program's
+// environment.
+func unsetEnv(e map[string]string) error {
+	for k := range e {
+		if err := os.Unsetenv(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uniqueServiceName is the Cloud Run Service name that will replace the existing service names in each codeBlock test.
+const uniqueServiceName = "unique_service_name"
+
+// uniqueImageURL is the Container Registry URL tag that will replace the existing Container Registry URL tag in each codeBlock test.
+const uniqueImageURL = "gcr.io/unique/tag"
+
+// uniqueLabels are the labels that will be added to the resources deployed in each codeBlock test.
+const uniqueLabels = "sst-run-id=unique,sst-created-at=1600000000"
+
+// execCmds returns the exec.Cmd of each of the provided Commands.
+func execCmds(cmds []Command) []*exec.Cmd {
+	var c []*exec.Cmd
+	for _, cmd := range cmds {
+		c = append(c, cmd.Cmd)
+	}
+	return c
+}
+
+// scriptCmd returns the exec.Cmd that runs the provided script through bash, as created by codeBlock.toCommands.
+func scriptCmd(script string) *exec.Cmd {
+	cmd := exec.Command("bash", "-euo", "pipefail", "-c", script)
+	cmd.Env = []string{
+		"CLOUDSDK_CORE_DISABLE_PROMPTS=1",
+		serviceNameEnvVar + "=" + uniqueServiceName,
+		imageURLEnvVar + "=" + uniqueImageURL,
+	}
+	return cmd
+}
+
+// withoutUnexported returns a copy of the provided Lifecycle without the unexported fields of its Commands, so that it
+// can be compared with reflect.DeepEqual.
+func withoutUnexported(l Lifecycle) Lifecycle {
+	var c Lifecycle
+	for _, cmd := range l {
+		c = append(c, Command{Cmd: cmd.Cmd, Source: cmd.Source})
+	}
+	return c
+}
+
+type toCommandsTest struct {
+	codeBlock codeBlock         // input code block
+	cmds      []*exec.Cmd       // expected result of codeBlock.toCommands
+	err       string            // expected string contained in return error of codeBlock.toCommands
+	env       map[string]string // map of environment variables to values for this test
+}
+
+var toCommandsTests = []toCommandsTest{
+	// single one-line command
+	{
+		codeBlock: codeBlock{lines: []string{
+			"echo hello world",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("echo", "hello", "world"),
+		},
+	},
+
+	// two one-line commands
+	{
+		codeBlock: codeBlock{lines: []string{
+			"echo line one",
+			"echo line two",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("echo", "line", "one"),
+			exec.Command("echo", "line", "two"),
+		},
+	},
+
+	// single multiline command
+	{
+		codeBlock: codeBlock{lines: []string{
+			"echo multi \\",
+			"line command",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("echo", "multi", "line", "command"),
+		},
+	},
+
+	// line cont char but code block closes at next line
+	{
+		codeBlock: codeBlock{lines: []string{
+			"echo multi \\",
+		}},
+		cmds: nil,
+		err:  errCodeBlockEndAfterLineCont.Error(),
+	},
+
+	// expand environment variable test
+	{
+		codeBlock: codeBlock{lines: []string{
+			"echo ${TEST_ENV}",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("echo", "hello", "world"),
+		},
+		env: map[string]string{
+			"TEST_ENV": "hello world",
+		},
+	},
+
+	// replace Cloud Run service name with provided name test
+	{
+		codeBlock: codeBlock{lines: []string{
+			"gcloud run services deploy hello_world",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("gcloud", "--quiet", "run", "services", "deploy", uniqueServiceName, "--update-labels="+uniqueLabels),
+		},
+	},
+
+	// replace Cloud Function name with provided name test
+	{
+		codeBlock: codeBlock{lines: []string{
+			"gcloud functions deploy hello_world --runtime=go113 --trigger-http",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("gcloud", "--quiet", "functions", "deploy", uniqueServiceName, "--runtime=go113", "--trigger-http",
+				"--update-labels="+uniqueLabels),
+		},
+	},
+
+	// replace Cloud Run job name with provided name test
+	{
+		codeBlock: codeBlock{lines: []string{
+			"gcloud run jobs create hello_world --image=gcr.io/hello/world --tasks=3",
+			"gcloud run jobs execute hello_world --wait",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("gcloud", "--quiet", "run", "jobs", "create", uniqueServiceName, "--image="+uniqueImageURL, "--tasks=3",
+				"--labels="+uniqueLabels),
+			exec.Command("gcloud", "--quiet", "run", "jobs", "execute", uniqueServiceName, "--wait"),
+		},
+	},
+
+	// labels merged into the deploy command's labels test
+	{
+		codeBlock: codeBlock{lines: []string{
+			"gcloud run deploy hello_world --labels=team=samples --image=gcr.io/hello/world",
+			"gcloud run jobs deploy hello_world --update-labels team=samples",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("gcloud", "--quiet", "run", "deploy", uniqueServiceName, "--labels=team=samples,"+uniqueLabels,
+				"--image="+uniqueImageURL),
+			exec.Command("gcloud", "--quiet", "run", "jobs", "deploy", uniqueServiceName, "--update-labels",
+				"team=samples,"+uniqueLabels),
+		},
+	},
+
+	// labels in shell mode test
+	{
+		codeBlock: codeBlock{
+			lines: []string{
+				"gcloud run deploy hello_world --labels \"team=samples\" --image=gcr.io/hello/world",
+				"gcloud run jobs create hello_world --image=gcr.io/hello/world > job.txt",
+			},
+			shell: "bash",
+		},
+		cmds: []*exec.Cmd{
+			scriptCmd("gcloud run deploy " + uniqueServiceName + " --labels \"team=samples\"," + uniqueLabels +
+				" --image=" + uniqueImageURL + "\n" +
+				"gcloud run jobs create " + uniqueServiceName + " --image=" + uniqueImageURL + " --labels=" +
+				uniqueLabels + " > job.txt"),
+		},
+	},
+
+	// App Engine deploy is made to a non-promoted version named after the service name test
+	{
+		codeBlock: codeBlock{lines: []string{
+			"gcloud app deploy app.yaml --version v1 --promote",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("gcloud", "--quiet", "app", "deploy", "--no-promote", "--version="+uniqueServiceName, "app.yaml"),
+		},
+	},
+
+	// App Engine deploy in shell mode test
+	{
+		codeBlock: codeBlock{
+			lines: []string{
+				"gcloud app deploy --version=v1 && echo deployed",
+			},
+			shell: "bash",
+		},
+		cmds: []*exec.Cmd{
+			scriptCmd("gcloud app deploy --no-promote --version=" + uniqueServiceName + " && echo deployed"),
+		},
+	},
+
+	// service name failsafe in shell mode stops at shell operators test
+	{
+		codeBlock: codeBlock{
+			lines: []string{
+				"gcloud run services describe hello --format=json | tee service.json > /dev/null",
+			},
+			shell: "bash",
+		},
+		cmds: []*exec.Cmd{
+			scriptCmd("gcloud run services describe " + uniqueServiceName +
+				" --format=json | tee service.json > /dev/null"),
+		},
+	},
+
+	// service name exported earlier in the README test
+	{
+		codeBlock: codeBlock{lines: []string{
+			"export CLOUD_RUN_SERVICE_NAME=hello",
+			"gcloud run services describe hello --format json",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("export", "CLOUD_RUN_SERVICE_NAME=hello"),
+			exec.Command("gcloud", "--quiet", "run", "services", "describe", uniqueServiceName, "--format", "json"),
+		},
+	},
+
+	// service name exported earlier in the README in shell mode test
+	{
+		codeBlock: codeBlock{
+			lines: []string{
+				"gcloud run services describe hello --format json",
+			},
+			shell: "bash",
+		},
+		cmds: []*exec.Cmd{
+			scriptCmd("gcloud run services describe " + uniqueServiceName + " --format json"),
+		},
+		env: map[string]string{
+			"CLOUD_RUN_SERVICE_NAME": "hello",
+		},
+	},
+
+	// replace Container Registry URL with provided URL test
+	{
+		codeBlock: codeBlock{lines: []string{
+			"gcloud builds submit --tag=gcr.io/hello/world",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("gcloud", "--quiet", "builds", "submit", "--tag="+uniqueImageURL),
+		},
+	},
+
+	// replace regional Container Registry URL with provided URL test
+	{
+		codeBlock: codeBlock{lines: []string{
+			"gcloud builds submit --tag=eu.gcr.io/hello/world",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("gcloud", "--quiet", "builds", "submit", "--tag="+uniqueImageURL),
+		},
+	},
+
+	// replace Artifact Registry URL with provided URL test
+	{
+		codeBlock: codeBlock{lines: []string{
+			"gcloud builds submit --tag us-central1-docker.pkg.dev/hello/samples/world:v1",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("gcloud", "--quiet", "builds", "submit", "--tag", uniqueImageURL),
+		},
+	},
+
+	// replace multiline GCR URL with provided URL test
+	{
+		codeBlock: codeBlock{lines: []string{
+			"gcloud builds submit --tag=gcr.io/hello/\\",
+			"world",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("gcloud", "--quiet", "builds", "submit", "--tag="+uniqueImageURL),
+		},
+	},
+
+	// replace Cloud Run service name and GCR URL with provided inputs test
+	{
+		codeBlock: codeBlock{lines: []string{
+			"gcloud run services deploy hello_world --image=gcr.io/hello/world",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("gcloud", "--quiet", "run", "services", "deploy", uniqueServiceName, "--image="+uniqueImageURL,
+				"--update-labels="+uniqueLabels),
+		},
+	},
+
+	// replace Cloud Run service name and GCR URL with `--image url` syntax test
+	{
+		codeBlock: codeBlock{lines: []string{
+			"gcloud run services deploy hello_world --image gcr.io/hello/world",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("gcloud", "--quiet", "run", "services", "deploy", uniqueServiceName, "--image", uniqueImageURL,
+				"--update-labels="+uniqueLabels),
+		},
+	},
+
+	// quoted argument containing spaces test
+	{
+		codeBlock: codeBlock{lines: []string{
+			`gcloud run deploy hello_world --set-env-vars="A=1, B=2" --command 'npm start'`,
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("gcloud", "--quiet", "run", "deploy", uniqueServiceName, "--set-env-vars=A=1, B=2", "--command", "npm start",
+				"--update-labels="+uniqueLabels),
+		},
+	},
+
+	// double spaces don't produce empty arguments test
+	{
+		codeBlock: codeBlock{lines: []string{
+			"echo  hello   world",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("echo", "hello", "world"),
+		},
+	},
+
+	// quoted environment variable isn't split test
+	{
+		codeBlock: codeBlock{lines: []string{
+			`echo "${TEST_ENV}"`,
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("echo", "hello world"),
+		},
+		env: map[string]string{
+			"TEST_ENV": "hello world",
+		},
+	},
+
+	// unterminated quote test
+	{
+		codeBlock: codeBlock{
+			lines: []string{
+				"echo hello",
+				`echo "hello world`,
+			},
+			startLine: 10,
+		},
+		err: "line 11: " + errUnterminatedDoubleQuote.Error(),
+	},
+
+	// code block run through a shell as a single script test
+	{
+		codeBlock: codeBlock{
+			lines: []string{
+				"export PROJECT=hello",
+				"gcloud builds submit --tag gcr.io/${PROJECT}/world",
+				"",
+				"gcloud run deploy hello_world --image gcr.io/${PROJECT}/world \\",
+				"--platform=managed | tee out.txt",
+			},
+			shell: "bash",
+		},
+		cmds: []*exec.Cmd{
+			scriptCmd("export PROJECT=hello\n" +
+				"gcloud builds submit --tag " + uniqueImageURL + "\n" +
+				"gcloud run deploy " + uniqueServiceName + " --image " + uniqueImageURL + " --platform=managed --update-labels=" +
+				uniqueLabels + " | tee out.txt"),
+		},
+	},
+
+	// replace double-quoted container image URL in script, leaving the quotes in place test
+	{
+		codeBlock: codeBlock{
+			lines: []string{"gcloud builds submit --tag \"gcr.io/${PROJECT}/world\""},
+			shell: "bash",
+		},
+		cmds: []*exec.Cmd{
+			scriptCmd("gcloud builds submit --tag \"" + uniqueImageURL + "\""),
+		},
+	},
+
+	// replace single-quoted container image URL in script, leaving the quotes in place test
+	{
+		codeBlock: codeBlock{
+			lines: []string{"docker push 'us-central1-docker.pkg.dev/hello/samples/world:v1'"},
+			shell: "bash",
+		},
+		cmds: []*exec.Cmd{
+			scriptCmd("docker push '" + uniqueImageURL + "'"),
+		},
+	},
+
+	// replace container image URLs followed by shell operators in script, leaving the operators in place test
+	{
+		codeBlock: codeBlock{
+			lines: []string{"docker push gcr.io/hello/world; docker pull eu.gcr.io/hello/world&& echo pulled"},
+			shell: "bash",
+		},
+		cmds: []*exec.Cmd{
+			scriptCmd("docker push " + uniqueImageURL + "; docker pull " + uniqueImageURL + "&& echo pulled"),
+		},
+	},
+	{
+		codeBlock: codeBlock{lines: []string{
+			"gcloud run services deploy hello_world --image=gcr.io/hello/world --add-cloudsql-instances=${TEST_CLOUD_SQL_CONNECTION}",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("gcloud", "--quiet", "run", "services", "deploy", uniqueServiceName, "--image="+uniqueImageURL, "--add-cloudsql-instances=project:region:instance", "--update-labels="+uniqueLabels),
+		},
+		env: map[string]string{
+			"TEST_CLOUD_SQL_CONNECTION": "project:region:instance",
+		},
+	},
+
+	// replace Cloud Run service name provided name in command with multiline arguments test
+	{
+		codeBlock: codeBlock{lines: []string{
+			"gcloud run services update hello_world --add-cloudsql-instances=\\",
+			"project:region:instance",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("gcloud", "--quiet", "run", "services", "update", uniqueServiceName, "--add-cloudsql-instances=project:region:instance"),
+		},
+	},
+
+	// replace Cloud Run service name provided name and expand environment variables in command with multiline arguments test
+	{
+		codeBlock: codeBlock{lines: []string{
+			"gcloud run services update hello_world --add-cloudsql-instances=\\",
+			"${TEST_CLOUD_SQL_CONNECTION}",
+		}},
+		cmds: []*exec.Cmd{
+			exec.Command("gcloud", "--quiet", "run", "services", "update", uniqueServiceName, "--add-cloudsql-instances=project:region:instance"),
+		},
+		env: map[string]string{
+			"TEST_CLOUD_SQL_CONNECTION": "project:region:instance",
+		},
+	},
+}
+
+func TestToCommands(t *testing.T) {
+	for i, tc := range toCommandsTests {
+		if len(tc.codeBlock.lines) == 0 {
+			continue
+		}
+
+		if err := setEnv(tc.env); err != nil {
+			t.Errorf("#%d: setEnv: %v", i, err)
+
+			if err = unsetEnv(tc.env); err != nil {
+				t.Errorf("#%d: unsetEnv: %v", i, err)
+			}
+
+			continue
+		}
+
+		cmds, err := tc.codeBlock.toCommands(uniqueServiceName, uniqueImageURL, uniqueLabels, NewEnv(""))
+
+		var errorMatch bool
+		if err == nil {
+			errorMatch = tc.err == ""
+		} else {
+			errorMatch = strings.Contains(err.Error(), tc.err)
+		}
+
+		if !errorMatch {
+			t.Errorf("#%d: error mismatch\nwant: %s\ngot: %v", i, tc.err, err)
+		}
+
+		if (errorMatch && err == nil) && !reflect.DeepEqual(execCmds(cmds), tc.cmds) {
+			t.Errorf("#%d: result mismatch\nwant: %#+v\ngot: %#+v", i, tc.cmds, execCmds(cmds))
+		}
+
+		if err := unsetEnv(tc.env); err != nil {
+			t.Errorf("#%d: unsetEnv: %v", i, err)
+		}
+	}
+}
+
+type parseREADMETest struct {
+	inFileName string    // input Markdown file
+	lifecycle  Lifecycle // expected result of parseREADME
+	err        error     // expected parseREADME return error
+}
+
+var parseREADMETests = []parseREADMETest{
+	// three code blocks, only two with comment code tags. one with one command, the other with two commands
+	{
+		inFileName: "readme_test.md",
+		lifecycle: Lifecycle{
+			{Cmd: exec.Command("echo", "hello", "world"), Source: Source{File: "readme_test.md", Line: 4, EndLine: 4, Text: "echo hello world"}},
+			{Cmd: exec.Command("echo", "line", "one"), Source: Source{File: "readme_test.md", Block: 1, Line: 10, EndLine: 10, Text: "echo line one"}},
+			{Cmd: exec.Command("echo", "line", "two"), Source: Source{File: "readme_test.md", Block: 1, Line: 11, EndLine: 11, Text: "echo line two"}},
+		},
+	},
+}
+
+func TestParseREADME(t *testing.T) {
+	for i, tc := range parseREADMETests {
+		if tc.inFileName == "" {
+			continue
+		}
+
+		// Cloud Run Service name and Container Registry URL tag replacement will be tested in TestToCommands
+		lifecycle, err := parseREADME(tc.inFileName, "", "", "")
+
+		if !errors.Is(err, tc.err) {
+			t.Errorf("#%d: error mismatch\nwant: %v\ngot: %v", i, tc.err, err)
+			continue
+		}
+
+		if err == nil && !reflect.DeepEqual(withoutUnexported(lifecycle), tc.lifecycle) {
+			t.Errorf("#%d: result mismatch\nwant: %#+v\ngot: %#+v", i, tc.lifecycle, lifecycle)
+			continue
+		}
+	}
+}
+
+type extractLifecycleTest struct {
+	in        string    // input Markdown string
+	lifecycle Lifecycle // expected results of extractLifecycle on in
+	err       error     // expected error
+}
+
+var extractLifecycleTests = []extractLifecycleTest{
+	// single code block
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"echo hello world\n" +
+			"```\n",
+		lifecycle: Lifecycle{
+			{Cmd: exec.Command("echo", "hello", "world"), Source: Source{Line: 3, EndLine: 3, Text: "echo hello world"}},
+		},
+	},
+
+	// two code blocks with markdown text in the middle
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"echo build command\n" +
+			"```\n" +
+			"markdown instructions\n" +
+			"[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"echo deploy command\n" +
+			"```\n",
+		lifecycle: Lifecycle{
+			{Cmd: exec.Command("echo", "build", "command"), Source: Source{Line: 3, EndLine: 3, Text: "echo build command"}},
+			{Cmd: exec.Command("echo", "deploy", "command"), Source: Source{Block: 1, Line: 8, EndLine: 8, Text: "echo deploy command"}},
+		},
+	},
+
+	// multiline command with the original text of the command kept in its source
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"echo one \\\n" +
+			"  ${UNSET_TEST_ENV}two\n" +
+			"echo three\n" +
+			"```\n",
+		lifecycle: Lifecycle{
+			{Cmd: exec.Command("echo", "one", "two"), Source: Source{Line: 3, EndLine: 4, Text: "echo one \\\n${UNSET_TEST_ENV}two"}},
+			{Cmd: exec.Command("echo", "three"), Source: Source{Line: 5, EndLine: 5, Text: "echo three"}},
+		},
+	},
+}
+
+func TestExtractLifecycle(t *testing.T) {
+	for i, tc := range extractLifecycleTests {
+		if tc.in == "" {
+			continue
+		}
+
+		s := bufio.NewScanner(strings.NewReader(tc.in))
+
+		// Cloud Run Service name and Container Registry URL tag replacement will be tested in TestToCommands
+		lifecycle, err := extractLifecycle(s, "", "", "")
+
+		if !errors.Is(err, tc.err) {
+			t.Errorf("#%d: error mismatch\nwant: %v\ngot: %v", i, tc.err, err)
+			continue
+		}
+
+		if err == nil && !reflect.DeepEqual(withoutUnexported(lifecycle), tc.lifecycle) {
+			t.Errorf("#%d: result mismatch\nwant: %#+v\ngot: %#+v", i, tc.lifecycle, lifecycle)
+		}
+	}
+}
+
+type extractCodeBlocksTest struct {
+	in         string      // input Markdown string
+	codeBlocks []codeBlock // expected result of extractCodeBlocks
+	err        error       // expected return error of extractCodeBlocks
+}
+
+var extractCodeBlocksTests = []extractCodeBlocksTest{
+	// single code block
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"echo hello world\n" +
+			"```\n",
+		codeBlocks: []codeBlock{
+			{
+				lines: []string{
+					"echo hello world",
+				},
+				startLine: 3,
+			},
+		},
+	},
+
+	// code block not closed
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"echo hello world\n",
+		codeBlocks: nil,
+		err:        errCodeBlockNotClosed,
+	},
+
+	// code block doesn't start immediately after code tag
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"not start of code block\n" +
+			"```\n" +
+			"echo hello world\n" +
+			"```\n",
+		codeBlocks: nil,
+		err:        errCodeBlockStartNotFound,
+	},
+
+	// EOF immediately after code tag
+	{
+		in: "instuctions\n" +
+			"[//]: # ({sst-run-unix})\n",
+		codeBlocks: nil,
+		err:        errEOFAfterCodeTag,
+	},
+
+	// single code block, two lines
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"echo line one\n" +
+			"echo line two\n" +
+			"```\n",
+		codeBlocks: []codeBlock{
+			{
+				lines: []string{
+					"echo line one",
+					"echo line two",
+				},
+				startLine: 3,
+			},
+		},
+	},
+
+	// two code blocks with markdown instructions in the middle
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"echo build command\n" +
+			"```\n" +
+			"markdown instructions\n" +
+			"[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"echo deploy command\n" +
+			"```\n",
+		codeBlocks: []codeBlock{
+			{
+				lines: []string{
+					"echo build command",
+				},
+				startLine: 3,
+			},
+			{
+				lines: []string{
+					"echo deploy command",
+				},
+				startLine: 8,
+				index:     1,
+			},
+		},
+	},
+
+	// two code blocks, but only one is annotated with code tag
+	{
+		in: "[//]: # ({sst-run-unix})\n" +
+			"```\n" +
+			"echo build and deploy command\n" +
+			"```\n" +
+			"markdown instructions\n" +
+			"```\n" +
+			"echo irrelevant command\n" +
+			"```\n",
+		codeBlocks: []codeBlock{
+			{
+				lines: []string{
+					"echo build and deploy command",
+				},
+				startLine: 3,
+			},
+		},
+	},
+
+	// one code block, but not annotated with code tag
+	{
+		in: "```\n" +
+			"echo hello world\n" +
+			"```\n",
+		codeBlocks: nil,
+	},
+
+	// code tag with a shell option
+	{
+		in: "[//]: # ({sst-run-unix shell=bash})\n" +
+			"```\n" +
+			"cd ..\n" +
+			"```\n",
+		codeBlocks: []codeBlock{
+			{
+				lines: []string{
+					"cd ..",
+				},
+				startLine: 3,
+				shell:     "bash",
+			},
+		},
+	},
+
+	// code tag with an unsupported shell
+	{
+		in: "[//]: # ({sst-run-unix shell=fish})\n" +
+			"```\n" +
+			"cd ..\n" +
+			"```\n",
+		err: errUnsupportedShell,
+	},
+
+	// code tag with an unknown option
+	{
+		in: "[//]: # ({sst-run-unix verbose})\n" +
+			"```\n" +
+			"cd ..\n" +
+			"```\n",
+		err: errUnknownCodeTagOption,
+	},
+}
+
+func TestExtractCodeBlocks(t *testing.T) {
+	for i, tc := range extractCodeBlocksTests {
+		if tc.in == "" {
+			continue
+		}
+
+		s := bufio.NewScanner(strings.NewReader(tc.in))
+		codeBlocks, err := extractCodeBlocks(s)
+
+		if !errors.Is(err, tc.err) {
+			t.Errorf("#%d: error mismatch\nwant: %v\ngot: %v", i, tc.err, err)
+			continue
+		}
+
+		if err == nil && !reflect.DeepEqual(codeBlocks, tc.codeBlocks) {
+			t.Errorf("#%d: result mismatch\nwant: %#+v\ngot: %#+v", i, tc.codeBlocks, codeBlocks)
+		}
+	}
+}
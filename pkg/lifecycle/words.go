@@ -0,0 +1,210 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var (
+	errUnterminatedSingleQuote = fmt.Errorf("unexpected end of command: unterminated single quote")
+	errUnterminatedDoubleQuote = fmt.Errorf("unexpected end of command: unterminated double quote")
+	errTrailingBackslash       = fmt.Errorf("unexpected end of command: nothing to escape after backslash")
+)
+
+// doubleQuoteEscapable holds the characters that a backslash escapes inside double quotes. Before any other character,
+// the backslash is kept literally.
+const doubleQuoteEscapable = "$`\"\\\n"
+
+// splitWords splits a command line into words following POSIX shell word-splitting rules. Words are separated by
+// unquoted white space. Single quotes preserve every character literally, double quotes preserve every character
+// except for parameter expansions and backslash escapes of $, `, ", \, and newlines, and an unquoted backslash
+// preserves the next character literally. Adjacent quoted and unquoted segments are concatenated into a single word. An
+// unquoted # at the start of a word starts a comment that runs to the end of the line.
+//
+// If mapping is not nil, parameter expansions in the form of $var and ${var} outside of single quotes are replaced by
+// mapping(var). The results of unquoted expansions are further split into multiple words on white space. If mapping
+// is nil, $ is treated like any other character.
+func splitWords(s string, mapping func(string) string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+
+	// inWord tracks whether a word has been started, so that empty quoted strings still produce a word.
+	inWord := false
+	flush := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+
+		case r == '#' && !inWord:
+			return words, nil
+
+		case r == '\\':
+			i++
+			if i >= len(rs) {
+				return nil, errTrailingBackslash
+			}
+
+			word.WriteRune(rs[i])
+			inWord = true
+
+		case r == '\'':
+			end := indexRune(rs, i+1, '\'')
+			if end < 0 {
+				return nil, errUnterminatedSingleQuote
+			}
+
+			word.WriteString(string(rs[i+1 : end]))
+			inWord = true
+			i = end
+
+		case r == '"':
+			inWord = true
+			for i++; i < len(rs) && rs[i] != '"'; i++ {
+				switch {
+				case rs[i] == '\\' && i+1 < len(rs) && strings.ContainsRune(doubleQuoteEscapable, rs[i+1]):
+					i++
+					word.WriteRune(rs[i])
+				case rs[i] == '$' && mapping != nil:
+					v, n := expandParameter(rs[i:], mapping)
+					if n == 0 {
+						word.WriteRune('$')
+						continue
+					}
+
+					word.WriteString(v)
+					i += n - 1
+				default:
+					word.WriteRune(rs[i])
+				}
+			}
+
+			if i >= len(rs) {
+				return nil, errUnterminatedDoubleQuote
+			}
+
+		case r == '$' && mapping != nil:
+			v, n := expandParameter(rs[i:], mapping)
+			if n == 0 {
+				word.WriteRune('$')
+				inWord = true
+				continue
+			}
+			i += n - 1
+
+			// Unquoted expansions are split into fields on white space.
+			if strings.IndexFunc(v, unicode.IsSpace) == 0 {
+				flush()
+			}
+
+			for j, f := range strings.Fields(v) {
+				if j > 0 {
+					flush()
+				}
+
+				word.WriteString(f)
+				inWord = true
+			}
+
+			if v != "" && strings.LastIndexFunc(v, unicode.IsSpace) == len(v)-1 {
+				flush()
+			}
+
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	flush()
+	return words, nil
+}
+
+// expandParameter expands the parameter expansion at the start of rs, which must start with a $. It returns the
+// expanded value and the number of runes the expansion took up. If rs doesn't start with a valid parameter expansion,
+// 0 is returned as the number of runes.
+func expandParameter(rs []rune, mapping func(string) string) (string, int) {
+	if len(rs) < 2 {
+		return "", 0
+	}
+
+	if rs[1] == '{' {
+		end := indexRune(rs, 2, '}')
+		if end < 0 || !isName(rs[2:end]) {
+			return "", 0
+		}
+
+		return mapping(string(rs[2:end])), end + 1
+	}
+
+	if unicode.IsDigit(rs[1]) {
+		return mapping(string(rs[1])), 2
+	}
+
+	n := 1
+	for n < len(rs) && isNameRune(rs[n], n == 1) {
+		n++
+	}
+
+	if n == 1 {
+		return "", 0
+	}
+
+	return mapping(string(rs[1:n])), n
+}
+
+// isName reports whether rs is a valid shell variable name.
+func isName(rs []rune) bool {
+	if len(rs) == 0 {
+		return false
+	}
+
+	for i, r := range rs {
+		if !isNameRune(r, i == 0) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isNameRune reports whether r can be part of a shell variable name. The first rune of a name can't be a digit.
+func isNameRune(r rune, first bool) bool {
+	return r == '_' || unicode.IsLetter(r) || (!first && unicode.IsDigit(r))
+}
+
+// indexRune returns the index of the first instance of r in rs at or after start, or -1 if there is none.
+func indexRune(rs []rune, start int, r rune) int {
+	for i := start; i < len(rs); i++ {
+		if rs[i] == r {
+			return i
+		}
+	}
+
+	return -1
+}
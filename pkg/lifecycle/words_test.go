@@ -0,0 +1,124 @@
+package lifecycle
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type splitWordsTest struct {
+	in    string   // input command line
+	words []string // expected result of splitWords
+	err   error    // expected return error of splitWords
+}
+
+// splitWordsEnv is the environment parameter expansions are expanded against in TestSplitWords.
+var splitWordsEnv = map[string]string{
+	"NAME":   "world",
+	"SPACED": " hello  world ",
+}
+
+var splitWordsTests = []splitWordsTest{
+	// plain words
+	{
+		in:    "echo hello world",
+		words: []string{"echo", "hello", "world"},
+	},
+
+	// repeated and surrounding white space
+	{
+		in:    "  echo \thello   world  ",
+		words: []string{"echo", "hello", "world"},
+	},
+
+	// single quotes
+	{
+		in:    `echo 'hello  "$NAME"'`,
+		words: []string{"echo", `hello  "$NAME"`},
+	},
+
+	// double quotes with expansions and escapes
+	{
+		in:    `echo "hello \"$NAME\" \n \$"`,
+		words: []string{"echo", `hello "world" \n $`},
+	},
+
+	// backslash escapes outside of quotes
+	{
+		in:    `echo hello\ world \'`,
+		words: []string{"echo", "hello world", "'"},
+	},
+
+	// adjacent quoted segments
+	{
+		in:    `--set-env-vars="A=1, "'B=2'C`,
+		words: []string{"--set-env-vars=A=1, B=2C"},
+	},
+
+	// empty quoted words
+	{
+		in:    `echo "" ''`,
+		words: []string{"echo", "", ""},
+	},
+
+	// unquoted expansion is split into fields
+	{
+		in:    "echo a${SPACED}b",
+		words: []string{"echo", "a", "hello", "world", "b"},
+	},
+
+	// unset variables expand to nothing
+	{
+		in:    "echo $UNSET ${UNSET}x",
+		words: []string{"echo", "x"},
+	},
+
+	// $ not followed by a name
+	{
+		in:    "echo $ ${",
+		words: []string{"echo", "$", "${"},
+	},
+
+	// comments
+	{
+		in:    "echo hello#world # comment",
+		words: []string{"echo", "hello#world"},
+	},
+
+	// unterminated single quote
+	{
+		in:  "echo 'hello",
+		err: errUnterminatedSingleQuote,
+	},
+
+	// unterminated double quote
+	{
+		in:  `echo "hello\"`,
+		err: errUnterminatedDoubleQuote,
+	},
+
+	// trailing backslash
+	{
+		in:  `echo \`,
+		err: errTrailingBackslash,
+	},
+}
+
+func TestSplitWords(t *testing.T) {
+	mapping := func(name string) string {
+		return splitWordsEnv[name]
+	}
+
+	for i, tc := range splitWordsTests {
+		words, err := splitWords(tc.in, mapping)
+
+		if !errors.Is(err, tc.err) {
+			t.Errorf("#%d: error mismatch\nwant: %v\ngot: %v", i, tc.err, err)
+			continue
+		}
+
+		if err == nil && !reflect.DeepEqual(words, tc.words) {
+			t.Errorf("#%d: result mismatch\nwant: %q\ngot: %q", i, tc.words, words)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sample
+
+import (
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/config"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ChangedFiles returns the absolute locations of the files of the git repository containing the provided directory
+// that changed between the provided git ref and HEAD, as reported by git diff --name-only. Only the changes made on
+// HEAD's side since the merge base of the ref and HEAD are included, as in a pull request against the ref.
+func ChangedFiles(e util.Executor, dir, ref string) ([]string, error) {
+	top, err := util.ExecCommand(e, exec.Command("git", "rev-parse", "--show-toplevel"), dir)
+	if err != nil {
+		return nil, fmt.Errorf("getting top-level directory of git repository: %w", err)
+	}
+
+	out, err := util.ExecCommand(e, exec.Command("git", "diff", "--name-only", ref+"...HEAD"), dir)
+	if err != nil {
+		return nil, fmt.Errorf("listing files changed since %s: %w", ref, err)
+	}
+
+	var files []string
+	for _, f := range strings.Split(out, "\n") {
+		if f = strings.TrimSpace(f); f != "" {
+			files = append(files, filepath.Join(top, filepath.FromSlash(f)))
+		}
+	}
+
+	return files, nil
+}
+
+// Affected reports whether the sample located in the provided directory is affected by any of the provided changed
+// files: whether any of them is in the sample's directory, or is the README or test endpoints file referenced through
+// the readme or tests key of the sample's config file, wherever it's located.
+func Affected(dir string, changed []string) (bool, error) {
+	dir = realPath(dir)
+
+	referenced, err := referencedFiles(dir)
+	if err != nil {
+		return false, err
+	}
+
+	for _, f := range changed {
+		f = realPath(f)
+		if strings.HasPrefix(f, dir+string(filepath.Separator)) {
+			return true, nil
+		}
+
+		for _, r := range referenced {
+			if f == r {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// referencedFiles returns the absolute locations of the files referenced through the readme and tests keys of the
+// config file of the sample located in the provided directory, if any.
+func referencedFiles(dir string) ([]string, error) {
+	// The config file isn't validated, since the files it references may have been deleted by the changes.
+	cfg, err := config.Read(dir)
+	if err != nil {
+		return nil, fmt.Errorf("config.Read: %w", err)
+	}
+
+	var files []string
+	if cfg.Readme != "" {
+		files = append(files, realPath(cfg.ReadmePath(dir)))
+	}
+
+	if cfg.Tests != "" {
+		files = append(files, realPath(cfg.TestsPath(dir)))
+	}
+
+	return files, nil
+}
+
+// realPath returns the provided absolute path with symbolic links evaluated, so that paths reported by git and paths
+// provided on the command line can be compared. If the path doesn't exist, e.g. because it was deleted, its directory's
+// symbolic links are evaluated instead.
+func realPath(p string) string {
+	p = filepath.Clean(p)
+	if r, err := filepath.EvalSymlinks(p); err == nil {
+		return r
+	}
+
+	if d, err := filepath.EvalSymlinks(filepath.Dir(p)); err == nil {
+		return filepath.Join(d, filepath.Base(p))
+	}
+
+	return p
+}
@@ -0,0 +1,108 @@
+package sample
+
+import (
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type affectedTest struct {
+	dir     string   // directory of the sample, relative to the repository root
+	changed []string // changed files, relative to the repository root
+	want    bool     // expected result of Affected
+}
+
+var affectedTests = []affectedTest{
+	// file in the sample's directory
+	{
+		dir:     "run/hello",
+		changed: []string{"docs/index.md", "run/hello/main.go"},
+		want:    true,
+	},
+
+	// file in a sibling directory sharing a prefix
+	{
+		dir:     "run/hello",
+		changed: []string{"run/hello-world/main.go"},
+		want:    false,
+	},
+
+	// README referenced through the readme config key
+	{
+		dir:     "run/shared",
+		changed: []string{"run/README.md"},
+		want:    true,
+	},
+
+	// test endpoints file referenced through the tests config key
+	{
+		dir:     "run/shared",
+		changed: []string{"tests/shared.yaml"},
+		want:    true,
+	},
+
+	// unrelated README
+	{
+		dir:     "run/hello",
+		changed: []string{"run/README.md"},
+		want:    false,
+	},
+}
+
+func TestAffected(t *testing.T) {
+	root, err := ioutil.TempDir("", "sst-changed")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	for _, d := range []string{"run/hello", "run/shared"} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0755); err != nil {
+			t.Fatalf("os.MkdirAll: %v", err)
+		}
+	}
+
+	config := "readme: ../README.md\ntests: ../../tests/shared.yaml\n"
+	if err := ioutil.WriteFile(filepath.Join(root, "run", "shared", "config.yaml"), []byte(config), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile: %v", err)
+	}
+
+	for i, tc := range affectedTests {
+		var changed []string
+		for _, f := range tc.changed {
+			changed = append(changed, filepath.Join(root, f))
+		}
+
+		got, err := Affected(filepath.Join(root, tc.dir), changed)
+		if err != nil {
+			t.Errorf("#%d: Affected: %v", i, err)
+			continue
+		}
+
+		if got != tc.want {
+			t.Errorf("#%d: result mismatch\nwant: %t\ngot: %t", i, tc.want, got)
+		}
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	e := &util.FakeExecutor{
+		Responses: []util.FakeResponse{
+			{Match: "rev-parse --show-toplevel", Stdout: "/repo"},
+			{Match: "diff --name-only origin/main...HEAD", Stdout: "run/hello/main.go\nREADME.md"},
+		},
+	}
+
+	got, err := ChangedFiles(e, ".", "origin/main")
+	if err != nil {
+		t.Fatalf("ChangedFiles: %v", err)
+	}
+
+	want := []string{filepath.Join("/repo", "run", "hello", "main.go"), filepath.Join("/repo", "README.md")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ChangedFiles: result mismatch\nwant: %q\ngot: %q", want, got)
+	}
+}
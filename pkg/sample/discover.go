@@ -0,0 +1,164 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sample
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/lifecycle"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the name of the file, at the root of a repository, listing the directories Discover skips.
+const IgnoreFileName = ".sstignore"
+
+// The files whose presence in a directory makes it a testable sample, in the order they're reported in.
+var discoverySignals = []string{"README.md", "config.yaml", "config.yml", "app.yaml", "Dockerfile", "pom.xml"}
+
+// DiscoveredSample is a testable sample found by Discover.
+type DiscoveredSample struct {
+	// The directory of the sample, relative to the root Discover walked.
+	Dir string `json:"dir"`
+
+	// The files that make the directory a testable sample, e.g. README.md if it contains code tags, or Dockerfile.
+	Signals []string `json:"signals"`
+}
+
+// Discover walks the directory tree rooted at the provided directory and returns the testable samples it contains:
+// the directories containing a README.md with code tags, a config.yaml, an app.yaml, a Dockerfile, or a pom.xml, the
+// same signals lifecycle.NewLifecycle checks. The directories of discovered samples aren't walked further, since
+// they're tested as a whole. Hidden directories, and directories matching any of the provided ignore patterns (see
+// ReadIgnoreFile), are skipped.
+func Discover(root string, ignore []string) ([]DiscoveredSample, error) {
+	var samples []DiscoveredSample
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		if rel != "." && (strings.HasPrefix(info.Name(), ".") || ignored(filepath.ToSlash(rel), ignore)) {
+			return filepath.SkipDir
+		}
+
+		signals, err := sampleSignals(path)
+		if err != nil {
+			return err
+		}
+
+		if len(signals) == 0 {
+			return nil
+		}
+
+		samples = append(samples, DiscoveredSample{Dir: rel, Signals: signals})
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, fmt.Errorf("filepath.Walk: %w", err)
+	}
+
+	return samples, nil
+}
+
+// sampleSignals returns the files in the provided directory that make it a testable sample.
+func sampleSignals(dir string) ([]string, error) {
+	var signals []string
+	for _, s := range discoverySignals {
+		p := filepath.Join(dir, s)
+		if _, err := os.Stat(p); err != nil {
+			continue
+		}
+
+		// A README only makes a sample if build and deploy commands would be parsed from it.
+		if s == "README.md" {
+			ok, err := lifecycle.HasCodeTags(p)
+			if err != nil {
+				return nil, fmt.Errorf("lifecycle.HasCodeTags: %s: %w", p, err)
+			}
+
+			if !ok {
+				continue
+			}
+		}
+
+		signals = append(signals, s)
+	}
+
+	return signals, nil
+}
+
+// ignored reports whether the provided slash-separated directory path, relative to the root Discover walks, matches
+// any of the provided ignore patterns. Patterns containing a slash are matched against the whole path, and other
+// patterns against the directory's name.
+func ignored(rel string, patterns []string) bool {
+	for _, p := range patterns {
+		name := rel
+		if strings.Contains(p, "/") {
+			p = strings.Trim(p, "/")
+		} else {
+			name = filepath.Base(rel)
+		}
+
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReadIgnoreFile reads the ignore patterns from the ignore file at the provided location. Each non-empty line that
+// doesn't start with # is a glob pattern, in the syntax of filepath.Match, of directories Discover skips. A missing
+// ignore file isn't an error, since it's optional.
+func ReadIgnoreFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("os.Open: %w", err)
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if _, err := filepath.Match(line, ""); err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern %q: %w", path, line, err)
+		}
+		patterns = append(patterns, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("bufio.Scanner.Scan: %s: %w", path, err)
+	}
+
+	return patterns, nil
+}
@@ -0,0 +1,124 @@
+package sample
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type discoverTest struct {
+	files  map[string]string  // files of the repository, relative to its root, and their contents
+	ignore []string           // ignore patterns
+	want   []DiscoveredSample // expected discovered samples
+}
+
+var discoverTests = []discoverTest{
+	// every signal, and README without code tags
+	{
+		files: map[string]string{
+			"README.md":                "# Samples\n",
+			"run/hello/README.md":      "[//]: # ({sst-run-unix})\n```\ngcloud run deploy hello\n```\n",
+			"run/hello/Dockerfile":     "FROM scratch\n",
+			"run/config/config.yaml":   "readme: ../README.md\n",
+			"run/java/pom.xml":         "<project/>\n",
+			"appengine/hello/app.yaml": "runtime: go113\n",
+			"docs/README.md":           "# Docs\n",
+		},
+		want: []DiscoveredSample{
+			{Dir: filepath.Join("appengine", "hello"), Signals: []string{"app.yaml"}},
+			{Dir: filepath.Join("run", "config"), Signals: []string{"config.yaml"}},
+			{Dir: filepath.Join("run", "hello"), Signals: []string{"README.md", "Dockerfile"}},
+			{Dir: filepath.Join("run", "java"), Signals: []string{"pom.xml"}},
+		},
+	},
+
+	// nested directories of samples and hidden directories aren't walked
+	{
+		files: map[string]string{
+			"java/pom.xml":        "<project/>\n",
+			"java/module/pom.xml": "<project/>\n",
+			".github/Dockerfile":  "FROM scratch\n",
+		},
+		want: []DiscoveredSample{
+			{Dir: "java", Signals: []string{"pom.xml"}},
+		},
+	},
+
+	// ignore patterns
+	{
+		files: map[string]string{
+			"run/hello/Dockerfile":       "FROM scratch\n",
+			"run/broken/Dockerfile":      "FROM scratch\n",
+			"functions/hello/Dockerfile": "FROM scratch\n",
+			"testdata/x/Dockerfile":      "FROM scratch\n",
+			"run/testdata/y/Dockerfile":  "FROM scratch\n",
+		},
+		ignore: []string{"testdata", "/run/broken", "functions/*"},
+		want: []DiscoveredSample{
+			{Dir: filepath.Join("run", "hello"), Signals: []string{"Dockerfile"}},
+		},
+	},
+}
+
+func TestDiscover(t *testing.T) {
+	for i, tc := range discoverTests {
+		root, err := ioutil.TempDir("", "sst-discover")
+		if err != nil {
+			t.Fatalf("#%d: ioutil.TempDir: %v", i, err)
+		}
+
+		for name, contents := range tc.files {
+			p := filepath.Join(root, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				t.Fatalf("#%d: os.MkdirAll: %v", i, err)
+			}
+
+			if err := ioutil.WriteFile(p, []byte(contents), 0644); err != nil {
+				t.Fatalf("#%d: ioutil.WriteFile: %v", i, err)
+			}
+		}
+
+		got, err := Discover(root, tc.ignore)
+		os.RemoveAll(root)
+		if err != nil {
+			t.Errorf("#%d: Discover: %v", i, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("#%d: result mismatch\nwant: %v\ngot: %v", i, tc.want, got)
+		}
+	}
+}
+
+func TestReadIgnoreFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sst-discover")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, IgnoreFileName)
+	if patterns, err := ReadIgnoreFile(p); err != nil || patterns != nil {
+		t.Errorf("ReadIgnoreFile: got %q, %v for a missing ignore file, want no patterns and no error", patterns, err)
+	}
+
+	if err := ioutil.WriteFile(p, []byte("# broken samples\nrun/broken\n\ntestdata\n"), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile: %v", err)
+	}
+
+	want := []string{"run/broken", "testdata"}
+	if patterns, err := ReadIgnoreFile(p); err != nil || !reflect.DeepEqual(patterns, want) {
+		t.Errorf("ReadIgnoreFile: result mismatch\nwant: %q\ngot: %q, %v", want, patterns, err)
+	}
+
+	if err := ioutil.WriteFile(p, []byte("run/[\n"), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile: %v", err)
+	}
+
+	if _, err := ReadIgnoreFile(p); err == nil {
+		t.Errorf("ReadIgnoreFile: got nil error for an invalid pattern")
+	}
+}
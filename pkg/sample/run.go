@@ -0,0 +1,286 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sample
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/gcloud"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/lifecycle"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// How many times, and how often, the logs of a Cloud Run job execution are read before the lines expected with the
+// job.logs config key are considered missing.
+var (
+	jobLogsAttempts = 6
+	jobLogsInterval = 10 * time.Second
+)
+
+// ErrTestsFailed is returned by Validate if any of the sample's endpoint tests didn't pass.
+var ErrTestsFailed = errors.New("all tests did not pass")
+
+// Validation is the outcome of validating a deployed sample.
+type Validation struct {
+	// The root URL the sample is served at, if it's deployed as a gcloud.Service.
+	ServiceURL string
+
+	// The results of the endpoint tests, if they were run.
+	Tests *util.TestReport
+
+	// The outcome of executing the sample, if it's deployed as a Cloud Run job.
+	Execution *gcloud.JobExecution
+}
+
+// CleanupResult is the outcome of deleting a single resource created while testing a sample.
+type CleanupResult struct {
+	Resource string
+	Err      error
+}
+
+// Deploy executes the sample's build and deploy lifecycle in its Env and labels the created resources, unless they were
+// labeled by the lifecycle's deploy commands (see LabeledOnDeploy). It stops once the context is done, killing the
+// in-flight command, or once the timeouts.deploy config key's timeout expires. The results of the executed commands,
+// including the failed one, are returned.
+func (s *Sample) Deploy(ctx context.Context) ([]util.CommandResult, error) {
+	if d := s.Config.Timeouts.Deploy; d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	log.Printf("Building and deploying sample as %s\n", s.Service.Describe())
+	results, err := s.BuildDeployLifecycle.Execute(ctx, s.Executor, s.Env())
+	if err != nil {
+		return results, fmt.Errorf("building and deploying sample: %w", err)
+	}
+
+	// Resources whose deploy commands couldn't be rewritten to label them, e.g. ones deployed by a command of a shell
+	// script that couldn't be detected, are labeled once they're deployed instead.
+	if !s.LabeledOnDeploy() {
+		log.Println("Labeling created resources")
+		if err := s.Service.Label(s.Dir, s.Labels); err != nil {
+			return results, fmt.Errorf("labeling %s: %w", s.Service.Describe(), err)
+		}
+	}
+
+	if s.cloudContainerImageURL != "" {
+		if err := s.TagCloudContainerImage(); err != nil {
+			return results, fmt.Errorf("tagging container image: %w", err)
+		}
+	}
+
+	return results, nil
+}
+
+// Validate tests the deployed sample. If it's deployed as a Cloud Run job, the job is executed (see validateJob).
+// Otherwise, its TestEndpoints are requested, unless the skip.endpoints config key is set. Requests are authorized
+// with an identity token of the gcloud authorized account, unless the sample is Local. If any endpoint test doesn't
+// pass, an error wrapping ErrTestsFailed is returned. The returned Validation holds the outcome of the steps that were
+// taken, even if an error is returned.
+func (s *Sample) Validate(ctx context.Context) (*Validation, error) {
+	v := &Validation{}
+	if job, ok := s.Service.(*gcloud.CloudRunJob); ok {
+		return v, s.validateJob(ctx, job, v)
+	}
+
+	service, ok := s.Service.(gcloud.Service)
+	if !ok {
+		return v, fmt.Errorf("testing %s: unsupported resource", s.Service.Describe())
+	}
+
+	if s.TestEndpoints == nil {
+		log.Println("Skipping endpoint tests: skip.endpoints is set")
+		return v, nil
+	}
+
+	// Local containers don't check identity tokens, so none is needed.
+	var identToken string
+	if !s.Local {
+		log.Println("Getting identity token for gcloud auhtorized account")
+		a := append(util.GcloudCommonFlags, "auth", "print-identity-token")
+		var err error
+		identToken, err = util.ExecCommand(s.Executor, exec.Command("gcloud", a...), s.Dir)
+		if err != nil {
+			return v, fmt.Errorf("getting identity token for gcloud auhtorized account: %w", err)
+		}
+	}
+
+	log.Println("Checking endpoints for expected results")
+	url, err := service.URL(ctx, s.Dir)
+	if err != nil {
+		return v, fmt.Errorf("getting %s URL: %w", s.Service.Describe(), err)
+	}
+	v.ServiceURL = url
+
+	log.Printf("Validating %s endpoints for expected status codes\n", s.Service.Describe())
+	v.Tests, err = util.ValidateEndpoints(ctx, url, &s.TestEndpoints.Paths, identToken, s.Config.Timeouts.Request)
+	if err != nil {
+		return v, fmt.Errorf("validating %s endpoints for expected status codes: %w", s.Service.Describe(), err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return v, fmt.Errorf("validating %s endpoints: %w", s.Service.Describe(), err)
+	}
+
+	if !v.Tests.Passed() {
+		return v, ErrTestsFailed
+	}
+
+	return v, nil
+}
+
+// validateJob executes the provided Cloud Run job the sample was deployed as and waits for the execution to finish.
+// It checks that the number of tasks set with the job.tasks config key succeeded, or if it isn't set, that no task
+// failed, and that every line set with the job.logs config key is contained in a line logged by the execution. The
+// execution is recorded in the provided Validation.
+func (s *Sample) validateJob(ctx context.Context, job *gcloud.CloudRunJob, v *Validation) error {
+	log.Printf("Executing %s and waiting for the execution to finish\n", job.Describe())
+	execution, err := job.Execute(ctx, s.Dir)
+	if err != nil {
+		return fmt.Errorf("executing %s: %w", job.Describe(), err)
+	}
+	v.Execution = &execution
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("executing %s: %w", job.Describe(), err)
+	}
+
+	if want := s.Config.Job.Tasks; want != nil {
+		if execution.SucceededCount != *want {
+			return fmt.Errorf("execution %s: %d tasks succeeded, want %d", execution.Name, execution.SucceededCount,
+				*want)
+		}
+	} else if execution.FailedCount > 0 || execution.SucceededCount == 0 {
+		return fmt.Errorf("execution %s: %d tasks succeeded and %d failed out of %d", execution.Name,
+			execution.SucceededCount, execution.FailedCount, execution.TaskCount)
+	}
+
+	wantLogs := s.Config.Job.Logs
+	if len(wantLogs) == 0 {
+		return nil
+	}
+
+	log.Println("Checking job logs for expected lines")
+	for attempt := 1; ; attempt++ {
+		logs, err := job.Logs(s.Dir, execution.Name)
+		if err != nil {
+			return fmt.Errorf("reading %s logs: %w", job.Describe(), err)
+		}
+
+		missing := missingLogLines(logs, wantLogs)
+		if len(missing) == 0 {
+			return nil
+		}
+
+		// Logs can take a while to be ingested, so they're read again before giving up.
+		if attempt == jobLogsAttempts {
+			return fmt.Errorf("execution %s didn't log %q", execution.Name, missing)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("reading %s logs: %w", job.Describe(), ctx.Err())
+		case <-time.After(jobLogsInterval):
+		}
+	}
+}
+
+// missingLogLines returns the wanted lines that aren't contained in any of the provided log lines.
+func missingLogLines(logs, want []string) []string {
+	var missing []string
+	for _, w := range want {
+		found := false
+		for _, l := range logs {
+			if strings.Contains(l, w) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			missing = append(missing, w)
+		}
+	}
+
+	return missing
+}
+
+// Cleanup deletes the resources created while testing the sample: its Service, its container image, and the ones the
+// commands of its CleanupLifecycle delete. Every deletion is attempted, even if the sample wasn't deployed, and its
+// outcome is logged and returned. Deletions that fail because the resource doesn't exist, e.g. because the deploy
+// failed before creating it, are considered successful (see util.IsNotFound). An error is returned if any of the
+// other deletions failed.
+func (s *Sample) Cleanup() ([]CleanupResult, error) {
+	log.Println("Cleaning up created resources")
+
+	type deletion struct {
+		resource string
+		delete   func() error
+	}
+
+	deletions := []deletion{{s.Service.Describe(), func() error { return s.Service.Delete(s.Dir) }}}
+	if s.cloudContainerImageURL != "" {
+		d := deletion{"container image " + s.cloudContainerImageURL, s.DeleteCloudContainerImage}
+
+		// A local container image can only be deleted once the container running it is.
+		if s.Local {
+			deletions = append(deletions, d)
+		} else {
+			deletions = append([]deletion{d}, deletions...)
+		}
+	}
+
+	// The additional cleanup commands are executed independently of each other, so that one failing doesn't prevent
+	// the others from cleaning up.
+	for _, c := range s.CleanupLifecycle {
+		c := c
+		deletions = append(deletions, deletion{"with " + c.Source.String(), func() error {
+			_, err := lifecycle.Lifecycle{c}.Execute(context.Background(), s.Executor, s.Env())
+			return err
+		}})
+	}
+
+	var results []CleanupResult
+	var failed []string
+	for _, d := range deletions {
+		err := d.delete()
+		if util.IsNotFound(err) {
+			log.Printf("Nothing to delete for %s: not found\n", d.resource)
+			results = append(results, CleanupResult{Resource: d.resource})
+			continue
+		}
+		results = append(results, CleanupResult{Resource: d.resource, Err: err})
+
+		if err != nil {
+			log.Printf("Failed to delete %s: %v\n", d.resource, err)
+			failed = append(failed, d.resource)
+			continue
+		}
+
+		log.Printf("Deleted %s\n", d.resource)
+	}
+
+	if len(failed) > 0 {
+		return results, fmt.Errorf("failed to delete %s", strings.Join(failed, ", "))
+	}
+
+	return results, nil
+}
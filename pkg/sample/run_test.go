@@ -0,0 +1,217 @@
+package sample
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/config"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/gcloud"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type sampleTest struct {
+	config   config.Config // config of the sample
+	status   int           // status code returned by the fake Cloud Run service
+	err      error         // error expected to be wrapped by the error returned by Validate, if any
+	image    string        // expected prefix of the sample's container image URL
+	commands []string      // substrings of the commands expected to be executed, in order
+}
+
+var sampleTests = []sampleTest{
+	// endpoint passes, default registry
+	{
+		status: http.StatusOK,
+		image:  "gcr.io/test-project/",
+		commands: []string{
+			"gcloud --quiet builds submit --tag=gcr.io/test-project/",
+			"gcloud --quiet run deploy ",
+			"gcloud --quiet container images add-tag ",
+			"gcloud --quiet auth print-identity-token",
+			"gcloud --quiet run --platform=managed services describe ",
+			"gcloud --quiet container images delete gcr.io/test-project/",
+			"gcloud --quiet run services delete ",
+		},
+	},
+
+	// endpoint fails, Artifact Registry, region and cleanup command set
+	{
+		config: config.Config{
+			Registry: "us-central1-docker.pkg.dev/samples",
+			Region:   "us-east1",
+			Cleanup:  []string{"gcloud sql instances delete hello-db"},
+		},
+		status: http.StatusInternalServerError,
+		err:    ErrTestsFailed,
+		image:  "us-central1-docker.pkg.dev/test-project/samples/",
+		commands: []string{
+			"gcloud --quiet builds submit --tag=us-central1-docker.pkg.dev/test-project/samples/",
+			"gcloud --quiet run deploy ",
+			"gcloud --quiet artifacts docker tags add ",
+			"gcloud --quiet auth print-identity-token",
+			"'--format=value(status.url)' --region=us-east1",
+			"gcloud --quiet artifacts docker images delete ",
+			"--platform=managed --region=us-east1",
+			"gcloud --quiet sql instances delete hello-db",
+		},
+	},
+
+	// endpoint tests skipped
+	{
+		config: config.Config{Skip: config.Skip{Endpoints: true}},
+		status: http.StatusInternalServerError,
+		image:  "gcr.io/test-project/",
+		commands: []string{
+			"gcloud --quiet builds submit --tag=gcr.io/test-project/",
+			"gcloud --quiet run deploy ",
+			"gcloud --quiet container images add-tag ",
+			"gcloud --quiet container images delete gcr.io/test-project/",
+			"gcloud --quiet run services delete ",
+		},
+	},
+}
+
+// TestSample tests every sample in the same process at the same time, each with its own config.
+func TestSample(t *testing.T) {
+	var wg sync.WaitGroup
+	errs := make([]string, len(sampleTests))
+	for i, tc := range sampleTests {
+		wg.Add(1)
+		go func(i int, tc sampleTest) {
+			defer wg.Done()
+			errs[i] = testSample(tc)
+		}(i, tc)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != "" {
+			t.Errorf("#%d: %s", i, err)
+		}
+	}
+}
+
+// testSample deploys, validates, and cleans up a sample as described by the provided sampleTest, and returns a
+// description of the first mismatch, if any.
+func testSample(tc sampleTest) string {
+	dir, err := ioutil.TempDir("", "sst-sample")
+	if err != nil {
+		return fmt.Sprintf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(tc.status)
+	}))
+	defer ts.Close()
+
+	e := &util.FakeExecutor{
+		Responses: []util.FakeResponse{
+			{Match: "rev-parse", Stdout: "abc1234"},
+			{Match: "get-value core/project", Stdout: "test-project"},
+			{Match: "print-identity-token", Stdout: "test-token"},
+			{Match: "services describe", Stdout: ts.URL},
+		},
+	}
+
+	s, err := NewSample(dir, e, config.StaticLoader{Config: tc.config}, Options{})
+	if err != nil {
+		return fmt.Sprintf("NewSample: %v", err)
+	}
+
+	if !strings.HasPrefix(s.CloudContainerImageURL(), tc.image) {
+		return fmt.Sprintf("image mismatch\nwant prefix: %s\ngot: %s", tc.image, s.CloudContainerImageURL())
+	}
+
+	if _, err := s.Deploy(context.Background()); err != nil {
+		return fmt.Sprintf("Deploy: %v", err)
+	}
+
+	_, err = s.Validate(context.Background())
+	if (tc.err == nil && err != nil) || (tc.err != nil && !errors.Is(err, tc.err)) {
+		return fmt.Sprintf("Validate error mismatch\nwant: %v\ngot: %v", tc.err, err)
+	}
+
+	results, err := s.Cleanup()
+	if err != nil {
+		return fmt.Sprintf("Cleanup: %v", err)
+	}
+
+	if want := 2 + len(tc.config.Cleanup); len(results) != want {
+		return fmt.Sprintf("got %d cleanup results, want %d", len(results), want)
+	}
+
+	// The first two commands get the container image tag and the project.
+	cmds := e.Commands()[2:]
+	if len(cmds) != len(tc.commands) {
+		return fmt.Sprintf("commands mismatch\nwant: %q\ngot: %q", tc.commands, cmds)
+	}
+
+	for j, c := range tc.commands {
+		if !strings.Contains(cmds[j], c) {
+			return fmt.Sprintf("command %d mismatch\nwant: %s\ngot: %s", j, c, cmds[j])
+		}
+	}
+
+	return ""
+}
+
+func TestMissingLogLines(t *testing.T) {
+	logs := []string{"starting", "hello from task 0"}
+	want := []string{"hello from task", "goodbye"}
+	if got := missingLogLines(logs, want); len(got) != 1 || got[0] != "goodbye" {
+		t.Errorf("missingLogLines: result mismatch\nwant: [goodbye]\ngot: %q", got)
+	}
+}
+
+var validateJobTests = []struct {
+	tasks     *int   // job.tasks config key
+	execution string // JSON output of the fake job execution
+	err       string // expected error message, or empty if the job passes
+}{
+	// every task succeeded
+	{
+		execution: `{"metadata": {"name": "hello-abc12"}, "spec": {"taskCount": 2}, "status": {"succeededCount": 2}}`,
+	},
+
+	// a task failed
+	{
+		execution: `{"metadata": {"name": "hello-abc12"}, "spec": {"taskCount": 2}, "status": {"succeededCount": 1, "failedCount": 1}}`,
+		err:       "execution hello-abc12: 1 tasks succeeded and 1 failed out of 2",
+	},
+
+	// no task ran
+	{
+		execution: `{"metadata": {"name": "hello-abc12"}, "spec": {"taskCount": 1}, "status": {}}`,
+		err:       "execution hello-abc12: 0 tasks succeeded and 0 failed out of 1",
+	},
+
+	// fewer tasks succeeded than expected
+	{
+		tasks:     intPtr(3),
+		execution: `{"metadata": {"name": "hello-abc12"}, "spec": {"taskCount": 3}, "status": {"succeededCount": 2}}`,
+		err:       "execution hello-abc12: 2 tasks succeeded, want 3",
+	},
+}
+
+func TestValidateJob(t *testing.T) {
+	for i, tc := range validateJobTests {
+		e := &util.FakeExecutor{Responses: []util.FakeResponse{{Match: "jobs execute", Stdout: tc.execution}}}
+		s := &Sample{Config: &config.Config{Job: config.Job{Tasks: tc.tasks}}, Executor: e}
+
+		err := s.validateJob(context.Background(), &gcloud.CloudRunJob{Name: "hello", Executor: e}, &Validation{})
+		if (err == nil && tc.err != "") || (err != nil && err.Error() != tc.err) {
+			t.Errorf("#%d: error mismatch\nwant: %s\ngot: %v", i, tc.err, err)
+		}
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,379 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sample tests GCP samples end-to-end: it deploys a sample, validates the deployed sample, and cleans up the
+// resources it created.
+package sample
+
+import (
+	"errors"
+	"fmt"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/config"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/docker"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/gcloud"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/lifecycle"
+	"github.com/GoogleCloudPlatform/serverless-sample-tester/pkg/util"
+	"github.com/getkin/kin-openapi/openapi3"
+	"log"
+	"os/exec"
+	"strings"
+	"unicode"
+)
+
+const maxCloudContainerImageTagLen = 53
+
+// localImageRepository is the repository local container images are built in.
+const localImageRepository = "sst-local"
+
+// ErrSkipped is returned by NewSample if the sample's config file says to skip it.
+var ErrSkipped = errors.New("sample skipped")
+
+// Options are the settings of a sample that can be set outside of its config file, e.g. with command line flags. The
+// ones that are set override the sample's config file.
+type Options struct {
+	// The target the sample is tested on, config.TargetCloud or config.TargetLocal.
+	Target string
+
+	// The container registry the sample's container image is pushed to (see gcloud.ParseRegistry).
+	Registry string
+}
+
+// Sample represents a Google Cloud Platform sample and associated properties.
+type Sample struct {
+	Name string
+
+	// The local directory this sample is located in.
+	Dir string
+
+	// The name of the service this sample will deploy to.
+	ServiceName string
+
+	// The gcloud.Resource this sample will deploy to: a Cloud Run service, a Cloud Function if the sample's lifecycle
+	// deploys it with gcloud functions deploy, a Cloud Run job if it deploys it with gcloud run jobs deploy, or an App
+	// Engine version if it deploys it with gcloud app deploy. All but Cloud Run jobs are gcloud.Services.
+	Service gcloud.Resource
+
+	// The lifecycle for building and deploying this sample.
+	BuildDeployLifecycle lifecycle.Lifecycle
+
+	// The additional commands cleaning up the resources created by BuildDeployLifecycle, set with the cleanup config
+	// key.
+	CleanupLifecycle lifecycle.Lifecycle
+
+	// The sample's configuration, loaded by the config.Loader it was created with and overridden by its Options.
+	Config *config.Config
+
+	// The endpoint requests Validate tests the sample with, or nil if the sample isn't deployed as a gcloud.Service or
+	// the skip.endpoints config key is set.
+	TestEndpoints *openapi3.Swagger
+
+	// The util.Executor all of this sample's external commands are executed with.
+	Executor util.Executor
+
+	// The labels identifying the resources created while testing this sample.
+	Labels gcloud.RunLabels
+
+	// The container registry this sample's build container image is pushed to.
+	Registry gcloud.Registry
+
+	// Whether this sample is built and run locally with Docker instead of being deployed to GCP. If it is, Service is a
+	// *docker.Container and its container image is only built locally.
+	Local bool
+
+	// The URL location of this sample's build container image in Registry, or empty if no container image is built.
+	cloudContainerImageURL string
+}
+
+// NewSample creates a new sample object for the sample located in the provided local directory. Its config is loaded
+// with the provided config.Loader, usually a config.FileLoader, and overridden by the provided Options. If the sample
+// is tested on the local target, it's created with newLocalSample instead. External commands are executed with the
+// provided util.Executor. If the config says to skip the sample, an error wrapping ErrSkipped is returned.
+func NewSample(dir string, e util.Executor, l config.Loader, o Options) (*Sample, error) {
+	cfg, err := l.Load(dir)
+	if err != nil {
+		return nil, fmt.Errorf("config.Loader.Load: %w", err)
+	}
+
+	if cfg.File != "" {
+		log.Println("Config file found: " + cfg.File)
+	}
+
+	if err := (&config.Config{Target: o.Target, Registry: o.Registry}).Validate(dir); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+
+	if o.Target != "" {
+		cfg.Target = o.Target
+	}
+
+	if o.Registry != "" {
+		cfg.Registry = o.Registry
+	}
+
+	if cfg.Skip.Sample {
+		return nil, fmt.Errorf("%w: skip.sample is set in %s", ErrSkipped, cfg.File)
+	}
+
+	if cfg.TargetOrDefault() == config.TargetLocal {
+		if cfg.Skip.Local {
+			return nil, fmt.Errorf("%w: skip.local is set in %s", ErrSkipped, cfg.File)
+		}
+
+		return newLocalSample(dir, e, cfg)
+	}
+
+	name := sampleName(dir)
+
+	containerTag, err := cloudContainerImageTag(e, name, dir)
+	if err != nil {
+		return nil, fmt.Errorf("sample.cloudContainerImageTag: %s %s: %w", name, dir, err)
+	}
+
+	projectID, err := gcloud.Project(e, dir)
+	if err != nil {
+		return nil, fmt.Errorf("gcloud.Project: %w", err)
+	}
+
+	registry, err := gcloud.ParseRegistry(cfg.Registry)
+	if err != nil {
+		return nil, fmt.Errorf("gcloud.ParseRegistry: %w", err)
+	}
+	cloudContainerImageURL := registry.ImageURL(projectID, containerTag)
+
+	serviceName, err := gcloud.ServiceName(name)
+	if err != nil {
+		return nil, fmt.Errorf("gcloud.ServiceName: %s sample: %w", name, err)
+	}
+
+	labels, err := gcloud.NewRunLabels()
+	if err != nil {
+		return nil, fmt.Errorf("gcloud.NewRunLabels: %w", err)
+	}
+
+	buildDeployLifecycle, err := lifecycle.NewLifecycle(dir, serviceName, cloudContainerImageURL, labels.ServiceLabels(),
+		cfg)
+	if err != nil {
+		return nil, fmt.Errorf("lifecycle.NewLifecycle: %w", err)
+	}
+
+	cleanupLifecycle, err := lifecycle.NewCleanupLifecycle(cfg.File, cfg.Cleanup, serviceName, cloudContainerImageURL)
+	if err != nil {
+		return nil, fmt.Errorf("lifecycle.NewCleanupLifecycle: %w", err)
+	}
+
+	var service gcloud.Resource = &gcloud.CloudRunService{Name: serviceName, Region: cfg.Region, Executor: e}
+	switch {
+	case buildDeployLifecycle.DeploysCloudFunction():
+		log.Println("Build and deploy commands deploy a Cloud Function, no container image will be cleaned up")
+		service = &gcloud.CloudFunction{Name: serviceName, Region: cfg.Region, Executor: e}
+		cloudContainerImageURL = ""
+	case buildDeployLifecycle.DeploysAppEngine():
+		log.Println("Build and deploy commands deploy an App Engine version, no container image will be cleaned up")
+		service = &gcloud.AppEngineVersion{Version: serviceName, Executor: e}
+		cloudContainerImageURL = ""
+	case buildDeployLifecycle.DeploysCloudRunJob():
+		log.Println("Build and deploy commands deploy a Cloud Run job")
+		service = &gcloud.CloudRunJob{Name: serviceName, Region: cfg.Region, Executor: e}
+	}
+
+	s := &Sample{
+		Name:                   name,
+		Dir:                    dir,
+		ServiceName:            serviceName,
+		Service:                service,
+		BuildDeployLifecycle:   buildDeployLifecycle,
+		CleanupLifecycle:       cleanupLifecycle,
+		Config:                 cfg,
+		Executor:               e,
+		Labels:                 labels,
+		Registry:               registry,
+		cloudContainerImageURL: cloudContainerImageURL,
+	}
+
+	if err := s.loadTestEndpoints(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// newLocalSample creates a new sample object for the sample located in the provided local directory, with the provided
+// configuration, to be built and run locally with Docker instead of being deployed to GCP. External commands are
+// executed with the provided util.Executor. No gcloud commands are executed, so no GCP project is needed. The
+// additional cleanup commands aren't used, since they clean up GCP resources.
+func newLocalSample(dir string, e util.Executor, cfg *config.Config) (*Sample, error) {
+	name := sampleName(dir)
+
+	containerTag, err := cloudContainerImageTag(e, name, dir)
+	if err != nil {
+		return nil, fmt.Errorf("sample.cloudContainerImageTag: %s %s: %w", name, dir, err)
+	}
+	image := localImageRepository + "/" + containerTag
+
+	containerName, err := gcloud.ServiceName(name)
+	if err != nil {
+		return nil, fmt.Errorf("gcloud.ServiceName: %s sample: %w", name, err)
+	}
+
+	port, err := docker.FreePort()
+	if err != nil {
+		return nil, fmt.Errorf("docker.FreePort: %w", err)
+	}
+	container := &docker.Container{Name: containerName, Port: port, Executor: e}
+
+	s := &Sample{
+		Name:                   name,
+		Dir:                    dir,
+		ServiceName:            containerName,
+		Service:                container,
+		BuildDeployLifecycle:   lifecycle.NewLocalLifecycle(dir, image, container),
+		Config:                 cfg,
+		Executor:               e,
+		Local:                  true,
+		cloudContainerImageURL: image,
+	}
+
+	if err := s.loadTestEndpoints(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// loadTestEndpoints loads the sample's TestEndpoints with the OpenAPI document set with the tests config key, or the
+// default one, unless the sample isn't deployed as a gcloud.Service or the skip.endpoints config key is set.
+func (s *Sample) loadTestEndpoints() error {
+	if _, ok := s.Service.(gcloud.Service); !ok || s.Config.Skip.Endpoints {
+		return nil
+	}
+
+	log.Println("Loading test endpoints")
+	swagger, err := util.LoadTestEndpoints(s.Dir, s.Config.Tests)
+	if err != nil {
+		return fmt.Errorf("util.LoadTestEndpoints: %w", err)
+	}
+
+	s.TestEndpoints = swagger
+	return nil
+}
+
+// sampleName computes a sample name for a sample object. Right now, it's defined as a shortened version of the sample's
+// local directory. Its length is flexible based on the provided length of a suffix that will be appended to the end of
+// the name.
+func sampleName(dir string) string {
+	n := strings.ReplaceAll(dir, "/", "-")
+	return strings.ToLower(n)
+}
+
+// Env returns a new Env the sample's lifecycles are executed in. It holds the environment variables set with the env
+// config key, and the region set with the region config key as the run/region and functions/region gcloud properties.
+func (s *Sample) Env() *lifecycle.Env {
+	env := lifecycle.NewEnv(s.Dir)
+	for name, value := range s.Config.Env {
+		env.Setenv(name, value)
+	}
+
+	if s.Config.Region != "" {
+		env.Setenv("CLOUDSDK_RUN_REGION", s.Config.Region)
+		env.Setenv("CLOUDSDK_FUNCTIONS_REGION", s.Config.Region)
+	}
+
+	return env
+}
+
+// CloudContainerImageURL returns the URL location of the sample's build container image, or an empty string if the
+// sample doesn't build one, e.g. because it's deployed as a Cloud Function or to App Engine.
+func (s *Sample) CloudContainerImageURL() string {
+	return s.cloudContainerImageURL
+}
+
+// DeleteCloudContainerImage deletes the sample's container image off of its Registry.
+func (s *Sample) DeleteCloudContainerImage() error {
+	_, err := util.ExecCommand(s.Executor, s.DeleteCloudContainerImageCmd(), s.Dir)
+
+	if err != nil {
+		return fmt.Errorf("deleting %s container image: %w", s.registryName(), err)
+	}
+
+	return nil
+}
+
+// TagCloudContainerImage adds a tag holding the sample's labels to the sample's container image, so it can be found
+// and deleted later if the tool fails to clean it up.
+func (s *Sample) TagCloudContainerImage() error {
+	cmd := s.TagCloudContainerImageCmd()
+	if cmd == nil {
+		return nil
+	}
+
+	_, err := util.ExecCommand(s.Executor, cmd, s.Dir)
+
+	if err != nil {
+		return fmt.Errorf("tagging %s container image: %w", s.registryName(), err)
+	}
+
+	return nil
+}
+
+// LabeledOnDeploy reports whether the sample's build and deploy commands add its Labels to the resources they deploy.
+// If they don't, Deploy labels its Service once it's deployed instead.
+func (s *Sample) LabeledOnDeploy() bool {
+	return s.BuildDeployLifecycle.AddsLabels(s.Labels.ServiceLabels())
+}
+
+// TagCloudContainerImageCmd returns the external gcloud SDK command that TagCloudContainerImage executes, or nil if the
+// sample is Local, since local container images don't need to be found by sst cleanup.
+func (s *Sample) TagCloudContainerImageCmd() *exec.Cmd {
+	if s.Local {
+		return nil
+	}
+
+	return s.Registry.AddTagCmd(s.cloudContainerImageURL, s.Labels.ImageTag())
+}
+
+// DeleteCloudContainerImageCmd returns the external command that DeleteCloudContainerImage executes: a gcloud SDK
+// command, or a Docker CLI command if the sample is Local.
+func (s *Sample) DeleteCloudContainerImageCmd() *exec.Cmd {
+	if s.Local {
+		return exec.Command("docker", "rmi", s.cloudContainerImageURL)
+	}
+
+	return s.Registry.DeleteImageCmd(s.cloudContainerImageURL)
+}
+
+// registryName returns the name of the registry the sample's container image is in, for error messages.
+func (s *Sample) registryName() string {
+	if s.Local {
+		return "local"
+	}
+
+	return s.Registry.String()
+}
+
+// cloudContainerImageTag creates a container image tag for the provided sample. It concatenates the sample's name
+// with a short SHA of the sample repository's HEAD commit.
+func cloudContainerImageTag(e util.Executor, sampleName string, sampleDir string) (string, error) {
+	sha, err := util.ExecCommand(e, exec.Command("git", "rev-parse", "--verify", "--short", "HEAD"), sampleDir)
+	if err != nil {
+		return "", fmt.Errorf("getting short SHA for sample repository: %w", err)
+	}
+
+	if l := maxCloudContainerImageTagLen - len(sha) - 1; len(sampleName) > l {
+		sampleName = sampleName[len(sampleName)-l:]
+	}
+	sampleName = strings.TrimFunc(sampleName, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	tag := sampleName + "-" + sha
+	return tag, nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// shellSafeRegexp matches strings that don't need to be quoted to be used as a single shell word.
+	shellSafeRegexp = regexp.MustCompile(`^[\w@%+=:,./-]+$`)
+
+	// notFoundRegexp matches the output of commands that failed because the resource they act on doesn't exist, e.g.
+	// gcloud's NOT_FOUND errors or Docker's "No such container".
+	notFoundRegexp = regexp.MustCompile(`NOT_FOUND|MANIFEST_UNKNOWN|(?i)could not be found|cannot find|no such (?:image|container)`)
+)
+
+// GcloudCommonFlags is a slice of common flags that should be added as arguments to all executions of the external
+// gcloud command.
+var GcloudCommonFlags = []string{
+	"--quiet",
+}
+
+// CommandResult holds the outcome of executing an external command.
+type CommandResult struct {
+	// The command that was executed, formatted like exec.Cmd.String.
+	Command string `json:"command"`
+
+	// How long the command took to execute.
+	Duration time.Duration `json:"duration"`
+
+	// The exit code of the command, or -1 if it couldn't be started.
+	ExitCode int `json:"exitCode"`
+
+	// The combined stdout and stderr of the command.
+	Output string `json:"output"`
+
+	// The stdout of the command.
+	Stdout string `json:"-"`
+
+	// Where the command came from, e.g. README.md:42, if known.
+	Source string `json:"source,omitempty"`
+}
+
+// ExecCommand executes an exec.Cmd with the provided Executor. If the command exits successfully, its stdout will be
+// returned. If there's an error, the command's combined stdout and stderr will be returned in an error. The command
+// will be run in the provided directory.
+func ExecCommand(e Executor, cmd *exec.Cmd, dir string) (string, error) {
+	r, err := e.Run(context.Background(), cmd, dir)
+	if err != nil {
+		return "", err
+	}
+
+	return r.Stdout, nil
+}
+
+// IsNotFound reports whether the provided error, returned by an Executor, means the command failed because the resource
+// it acts on doesn't exist.
+func IsNotFound(err error) bool {
+	return err != nil && notFoundRegexp.MatchString(err.Error())
+}
+
+// CommandLine formats an exec.Cmd as a shell command line: its arguments, starting with the command name rather than
+// the resolved path to the executable, joined by spaces. Arguments containing characters that are special to the shell
+// are single-quoted.
+func CommandLine(cmd *exec.Cmd) string {
+	q := make([]string, len(cmd.Args))
+	for i, a := range cmd.Args {
+		q[i] = shellQuote(a)
+	}
+
+	return strings.Join(q, " ")
+}
+
+// shellQuote single-quotes a string if it contains characters that are special to the shell.
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+
+	if shellSafeRegexp.MatchString(s) {
+		return s
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
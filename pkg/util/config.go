@@ -0,0 +1,104 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+const passResponseDescription = "PASS"
+
+// defaultTestEndpointsFileNames are the file names, relative to the sample directory, that are searched for an OpenAPI
+// document describing the sample's test endpoints when one isn't explicitly configured.
+var defaultTestEndpointsFileNames = []string{
+	"openapi.yaml",
+	"openapi.yml",
+	"openapi.json",
+}
+
+// LoadTestEndpoints loads the test endpoint requests for the sample located in the provided directory into an
+// openapi3.Swagger object (see github.com/getkin/kin-openapi). If testsPath is not empty, the OpenAPI document at that
+// location is used. It can be absolute or relative to the sample directory. Otherwise, the sample directory is searched
+// for an openapi.yaml, openapi.yml, or openapi.json file. If none of those exist, a default test endpoint request
+// (a GET / request expecting a 200 status code) is used.
+func LoadTestEndpoints(sampleDir, testsPath string) (*openapi3.Swagger, error) {
+	if testsPath != "" {
+		if !filepath.IsAbs(testsPath) {
+			testsPath = filepath.Join(sampleDir, testsPath)
+		}
+
+		log.Printf("Using test endpoints in configured OpenAPI document: %s\n", testsPath)
+		return loadTestEndpointsFile(testsPath)
+	}
+
+	for _, n := range defaultTestEndpointsFileNames {
+		p := filepath.Join(sampleDir, n)
+		if _, err := os.Stat(p); err != nil {
+			continue
+		}
+
+		log.Printf("Using test endpoints in OpenAPI document: %s\n", p)
+		return loadTestEndpointsFile(p)
+	}
+
+	log.Println("No OpenAPI document found, using default test endpoint (GET /)")
+	return defaultTestEndpoints(), nil
+}
+
+// loadTestEndpointsFile loads and validates the OpenAPI document at the provided location.
+func loadTestEndpointsFile(path string) (*openapi3.Swagger, error) {
+	swagger, err := openapi3.NewSwaggerLoader().LoadSwaggerFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("openapi3.SwaggerLoader.LoadSwaggerFromFile: %s: %w", path, err)
+	}
+
+	if err := swagger.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("openapi3.Swagger.Validate: %s: %w", path, err)
+	}
+
+	if len(swagger.Paths) == 0 {
+		return nil, fmt.Errorf("%s: no paths defined", path)
+	}
+
+	return swagger, nil
+}
+
+// defaultTestEndpoints returns a default test endpoint request (a GET / request expecting a 200 status code) in an
+// openapi3.Swagger object.
+func defaultTestEndpoints() *openapi3.Swagger {
+	prd := passResponseDescription
+
+	return &openapi3.Swagger{
+		Paths: openapi3.Paths{
+			"/": &openapi3.PathItem{
+				Get: &openapi3.Operation{
+					Responses: openapi3.Responses{
+						"200": &openapi3.ResponseRef{
+							Value: &openapi3.Response{
+								ExtensionProps: openapi3.ExtensionProps{},
+								Description:    &prd,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
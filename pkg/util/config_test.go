@@ -0,0 +1,118 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+const testOpenAPIDoc = `openapi: 3.0.0
+info:
+  title: test
+  version: "1.0"
+paths:
+  /:
+    get:
+      responses:
+        "200":
+          description: OK
+  /submit:
+    post:
+      requestBody:
+        content:
+          application/json:
+            example:
+              hello: world
+      responses:
+        "201":
+          description: Created
+`
+
+type loadTestEndpointsTest struct {
+	files     map[string]string // files to create in the sample directory, mapped to their contents
+	testsPath string            // testsPath argument of LoadTestEndpoints
+	paths     []string          // expected paths in the loaded openapi3.Swagger
+	err       bool              // whether LoadTestEndpoints is expected to return an error
+}
+
+var loadTestEndpointsTests = []loadTestEndpointsTest{
+	// no OpenAPI document, fall back to default
+	{
+		paths: []string{"/"},
+	},
+
+	// openapi.yaml in sample directory
+	{
+		files: map[string]string{
+			"openapi.yaml": testOpenAPIDoc,
+		},
+		paths: []string{"/", "/submit"},
+	},
+
+	// configured relative tests path
+	{
+		files: map[string]string{
+			"test/spec.yaml": testOpenAPIDoc,
+		},
+		testsPath: "test/spec.yaml",
+		paths:     []string{"/", "/submit"},
+	},
+
+	// configured tests path doesn't exist
+	{
+		testsPath: "missing.yaml",
+		err:       true,
+	},
+
+	// invalid OpenAPI document
+	{
+		files: map[string]string{
+			"openapi.json": `{"openapi": "3.0.0", "paths": {"/": {"get": {}}}}`,
+		},
+		err: true,
+	},
+}
+
+func TestLoadTestEndpoints(t *testing.T) {
+	for i, tc := range loadTestEndpointsTests {
+		dir, err := ioutil.TempDir("", "sst-load-test-endpoints")
+		if err != nil {
+			t.Fatalf("#%d: ioutil.TempDir: %v", i, err)
+		}
+
+		for n, c := range tc.files {
+			p := filepath.Join(dir, n)
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				t.Fatalf("#%d: os.MkdirAll: %v", i, err)
+			}
+			if err := ioutil.WriteFile(p, []byte(c), 0644); err != nil {
+				t.Fatalf("#%d: ioutil.WriteFile: %v", i, err)
+			}
+		}
+
+		swagger, err := LoadTestEndpoints(dir, tc.testsPath)
+		os.RemoveAll(dir)
+
+		if (err != nil) != tc.err {
+			t.Errorf("#%d: error mismatch\nwant error: %t\ngot: %v", i, tc.err, err)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		var paths []string
+		for p := range swagger.Paths {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+
+		if !reflect.DeepEqual(paths, tc.paths) {
+			t.Errorf("#%d: result mismatch\nwant: %v\ngot: %v", i, tc.paths, paths)
+		}
+	}
+}
@@ -0,0 +1,456 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// test holds the openapi3.Operation and HTTP method associated with a single
+type test struct {
+	operation  *openapi3.Operation
+	httpMethod string
+}
+
+// The OpenAPI response extensions that specify additional assertions on a response body.
+const (
+	bodyContainsExtension = "x-sst-body-contains"
+	bodyRegexExtension    = "x-sst-body-regex"
+	bodyExampleExtension  = "x-sst-body-example"
+)
+
+// httpTimeout is the default timeout used for HTTP requests made to Cloud Run services.
+const httpTimeout = 10 * time.Second
+
+// ValidateEndpoints tests all paths (represented by openapi3.Paths) with all HTTP methods and given response bodies
+// and make sure they respond as expected. Requests are authorized with the provided identity token, unless it's empty.
+// Each request times out after the provided timeout, or after httpTimeout if it's zero. Once the provided context is
+// done, the in-flight request is canceled and no further requests are made. Returns a TestReport holding the result of
+// every test request.
+func ValidateEndpoints(ctx context.Context, serviceURL string, paths *openapi3.Paths, identityToken string, timeout time.Duration) (*TestReport, error) {
+	if timeout == 0 {
+		timeout = httpTimeout
+	}
+
+	var endpoints []string
+	for endpoint := range *paths {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+
+	report := &TestReport{}
+	for _, endpoint := range endpoints {
+		pathItem := (*paths)[endpoint]
+
+		log.Printf("Testing %s endpoint\n", endpoint)
+		tests := []test{
+			{pathItem.Connect, http.MethodConnect},
+			{pathItem.Delete, http.MethodDelete},
+			{pathItem.Get, http.MethodGet},
+			{pathItem.Head, http.MethodHead},
+			{pathItem.Options, http.MethodOptions},
+			{pathItem.Patch, http.MethodPatch},
+			{pathItem.Post, http.MethodPost},
+			{pathItem.Put, http.MethodPut},
+			{pathItem.Trace, http.MethodTrace},
+		}
+
+		for _, t := range tests {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+
+			results, err := validateEndpointOperation(ctx, serviceURL, endpoint, t.operation, t.httpMethod, identityToken, timeout)
+			if err != nil {
+				return report, fmt.Errorf("util.validateEndpointOperation: testing %s requests on %s: %w", t.httpMethod, serviceURL+endpoint, err)
+			}
+
+			report.Results = append(report.Results, results...)
+		}
+	}
+
+	return report, nil
+}
+
+// validateEndpointOperation validates a single endpoint and a single HTTP method, and ensures that the request --
+// including the provided sample request body -- elicits the expected response. One TestResult is returned for each
+// request body example.
+func validateEndpointOperation(ctx context.Context, serviceURL, endpoint string, operation *openapi3.Operation, httpMethod string, identityToken string, timeout time.Duration) ([]TestResult, error) {
+	if operation == nil {
+		return nil, nil
+	}
+	endpointURL := serviceURL + endpoint
+	log.Printf("Executing %s %s\n", httpMethod, endpointURL)
+
+	if operation.RequestBody == nil {
+		log.Println("Sending empty request body")
+
+		r, err := makeTestRequest(ctx, serviceURL, endpoint, httpMethod, "", "", operation, identityToken, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("util.makeTestRequest: testing %s request on %s: %w", httpMethod, endpointURL, err)
+		}
+
+		return []TestResult{r}, nil
+	}
+
+	reqBodies := operation.RequestBody.Value.Content
+
+	var mimeTypes []string
+	for mimeType := range reqBodies {
+		mimeTypes = append(mimeTypes, mimeType)
+	}
+	sort.Strings(mimeTypes)
+
+	var results []TestResult
+	for _, mimeType := range mimeTypes {
+		reqBodyStr, err := exampleString(reqBodies[mimeType].Example)
+		if err != nil {
+			return results, fmt.Errorf("util.exampleString: %s %s request body example on %s: %w", httpMethod, mimeType, endpointURL, err)
+		}
+		log.Printf("Sending %s: %s", mimeType, reqBodyStr)
+
+		r, err := makeTestRequest(ctx, serviceURL, endpoint, httpMethod, mimeType, reqBodyStr, operation, identityToken, timeout)
+		if err != nil {
+			return results, fmt.Errorf("util.makeTestRequest: testing %s %s request on %s: %w", httpMethod, mimeType, endpointURL, err)
+		}
+
+		results = append(results, r)
+	}
+
+	return results, nil
+}
+
+// exampleString converts an OpenAPI example value into the string that should be sent as a request body. String
+// examples are used as-is; any other value (e.g. a YAML or JSON object) is marshalled into JSON.
+func exampleString(example interface{}) (string, error) {
+	switch e := example.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return e, nil
+	}
+
+	b, err := json.Marshal(example)
+	if err != nil {
+		return "", fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	return string(b), nil
+}
+
+// makeTestRequest makes a single test request and returns its TestResult. The test fails if the request couldn't be
+// made, e.g. because it timed out or the connection was refused, if the returned status code wasn't included in the
+// provided openapi3.Operation expected responses, or if the response doesn't match the expected response or the checks
+// declared for it are malformed. An error is returned instead if the request failed because the provided context is
+// done.
+func makeTestRequest(ctx context.Context, serviceURL, endpoint, httpMethod, mimeType, reqBody string, operation *openapi3.Operation, identityToken string, timeout time.Duration) (TestResult, error) {
+	result := TestResult{
+		Path:        endpoint,
+		Method:      httpMethod,
+		MimeType:    mimeType,
+		RequestBody: reqBody,
+	}
+	for status := range operation.Responses {
+		result.ExpectedStatuses = append(result.ExpectedStatuses, status)
+	}
+	sort.Strings(result.ExpectedStatuses)
+
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, httpMethod, serviceURL+endpoint, strings.NewReader(reqBody))
+	if err != nil {
+		return result, fmt.Errorf("http.NewRequest: %w", err)
+	}
+
+	if identityToken != "" {
+		req.Header.Add("Authorization", "Bearer "+identityToken)
+	}
+	req.Header.Add("content-type", mimeType)
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil && ctx.Err() != nil {
+		return result, fmt.Errorf("http.Client.Do: %w", ctx.Err())
+	}
+	if err != nil {
+		log.Printf("Request failed: %v: FAIL\n", err)
+		result.Latency = time.Since(start)
+		result.FailureReason = fmt.Sprintf("request failed: %v", err)
+		return result, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	defer resp.Body.Close()
+	result.Latency = time.Since(start)
+	result.StatusCode = resp.StatusCode
+	if err != nil {
+		log.Printf("Reading response body failed: %v: FAIL\n", err)
+		result.FailureReason = fmt.Sprintf("reading response body: %v", err)
+		return result, nil
+	}
+	result.ResponseSnippet = responseSnippet(body)
+
+	statusCode := strconv.Itoa(resp.StatusCode)
+	log.Printf("Status code: %s\n", statusCode)
+
+	val, ok := operation.Responses[statusCode]
+	if !ok {
+		log.Println("Unknown response description: FAIL")
+		result.FailureReason = fmt.Sprintf("unexpected status code %s", statusCode)
+		return result, nil
+	}
+	log.Printf("Response description: %s\n", *val.Value.Description)
+
+	reason, err := checkResponse(req, resp, body, operation, val.Value)
+	if err != nil {
+		// The checks of this response are malformed, e.g. an invalid x-sst-body-regex, which only fails this test.
+		reason = fmt.Sprintf("invalid response checks: %v", err)
+	}
+
+	if reason != "" {
+		log.Printf("Unexpected response: %s: FAIL\n", reason)
+		result.FailureReason = reason
+	}
+
+	return result, nil
+}
+
+// checkResponse checks the response headers, Content-Type, and body against the matching openapi3.Response. A
+// non-empty failure reason is returned if the response didn't pass any of these checks.
+func checkResponse(req *http.Request, resp *http.Response, body []byte, operation *openapi3.Operation, response *openapi3.Response) (string, error) {
+	reason, err := checkResponseHeaders(resp.Header, response)
+	if err != nil || reason != "" {
+		return reason, err
+	}
+
+	if reason := checkResponseContentType(resp.Header, response); reason != "" {
+		return reason, nil
+	}
+
+	return checkResponseBody(req, resp, body, operation, response)
+}
+
+// checkResponseHeaders checks that every header declared in the openapi3.Response is present in the response. If a
+// header declares a schema, its value must also match that schema.
+func checkResponseHeaders(header http.Header, response *openapi3.Response) (string, error) {
+	for name, ref := range response.Headers {
+		if _, ok := header[http.CanonicalHeaderKey(name)]; !ok {
+			return fmt.Sprintf("header %s is missing", name), nil
+		}
+
+		if ref.Value == nil || ref.Value.Schema == nil || ref.Value.Schema.Value == nil {
+			continue
+		}
+		schema := ref.Value.Schema.Value
+
+		value := header.Get(name)
+		v, err := headerValue(value, schema)
+		if err != nil {
+			return fmt.Sprintf("header %s: %q is not of type %s", name, value, schema.Type), nil
+		}
+
+		if err := schema.VisitJSON(v); err != nil {
+			return fmt.Sprintf("header %s: %q doesn't match the schema: %v", name, value, err), nil
+		}
+	}
+
+	return "", nil
+}
+
+// headerValue converts a header value into the type declared by the provided schema so it can be validated by it.
+func headerValue(value string, schema *openapi3.Schema) (interface{}, error) {
+	switch schema.Type {
+	case "integer", "number":
+		return strconv.ParseFloat(value, 64)
+	case "boolean":
+		return strconv.ParseBool(value)
+	}
+
+	return value, nil
+}
+
+// checkResponseContentType checks that the response Content-Type matches one of the media types declared in the
+// openapi3.Response, if any are declared. Media type ranges such as text/* are supported.
+func checkResponseContentType(header http.Header, response *openapi3.Response) string {
+	if len(response.Content) == 0 {
+		return ""
+	}
+
+	contentType := header.Get("Content-Type")
+	if response.Content.Get(contentType) != nil {
+		return ""
+	}
+
+	var declared []string
+	for mt := range response.Content {
+		declared = append(declared, mt)
+	}
+	sort.Strings(declared)
+
+	return fmt.Sprintf("Content-Type %q doesn't match any of the declared media types: %s", contentType,
+		strings.Join(declared, ", "))
+}
+
+// checkResponseBody checks the response body against the content schema of the matching openapi3.Response using
+// openapi3filter. It also checks the body against the sst extensions of the openapi3.Response: x-sst-body-contains
+// (a substring that must be present in the body), x-sst-body-regex (a regular expression the body must match), and
+// x-sst-body-example (if true, the body must equal the example declared for the response's media type). A non-empty
+// failure reason is returned if the body didn't pass any of these checks. An error is returned if the checks
+// themselves are malformed.
+func checkResponseBody(req *http.Request, resp *http.Response, body []byte, operation *openapi3.Operation, response *openapi3.Response) (string, error) {
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request: req,
+			Route: &openapi3filter.Route{
+				Method:    req.Method,
+				Operation: operation,
+			},
+		},
+		Status: resp.StatusCode,
+		Header: resp.Header,
+	}
+	input.SetBodyBytes(body)
+
+	if err := openapi3filter.ValidateResponse(req.Context(), input); err != nil {
+		return err.Error(), nil
+	}
+
+	contains, ok, err := extensionString(response.Extensions, bodyContainsExtension)
+	if err != nil {
+		return "", err
+	}
+	if ok && !strings.Contains(string(body), contains) {
+		return fmt.Sprintf("body does not contain %q", contains), nil
+	}
+
+	expr, ok, err := extensionString(response.Extensions, bodyRegexExtension)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return "", fmt.Errorf("regexp.Compile: %s extension: %w", bodyRegexExtension, err)
+		}
+
+		if !re.Match(body) {
+			return fmt.Sprintf("body does not match regular expression %q", expr), nil
+		}
+	}
+
+	matchExample, _, err := extensionBool(response.Extensions, bodyExampleExtension)
+	if err != nil {
+		return "", err
+	}
+	if matchExample {
+		contentType := resp.Header.Get("Content-Type")
+		mediaType := response.Content.Get(contentType)
+		if mediaType == nil || mediaType.Example == nil {
+			return "", fmt.Errorf("%s extension: no example declared for response content type %q", bodyExampleExtension, contentType)
+		}
+
+		eq, err := bodyEqualsExample(body, contentType, mediaType.Example)
+		if err != nil {
+			return "", fmt.Errorf("util.bodyEqualsExample: %w", err)
+		}
+
+		if !eq {
+			return "body does not equal the declared example", nil
+		}
+	}
+
+	return "", nil
+}
+
+// bodyEqualsExample compares a response body to an OpenAPI example value. JSON bodies are compared semantically; all
+// other bodies are compared as strings, ignoring leading and trailing white space.
+func bodyEqualsExample(body []byte, contentType string, example interface{}) (bool, error) {
+	if !strings.Contains(contentType, "json") {
+		e, err := exampleString(example)
+		if err != nil {
+			return false, fmt.Errorf("util.exampleString: %w", err)
+		}
+
+		return strings.TrimSpace(string(body)) == strings.TrimSpace(e), nil
+	}
+
+	var b interface{}
+	if err := json.Unmarshal(body, &b); err != nil {
+		return false, nil
+	}
+
+	// Round trip the example through JSON so that both values are made up of the same types.
+	eb, err := json.Marshal(example)
+	if err != nil {
+		return false, fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	var e interface{}
+	if err := json.Unmarshal(eb, &e); err != nil {
+		return false, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	return reflect.DeepEqual(b, e), nil
+}
+
+// extensionString returns the string value of the OpenAPI extension with the provided name, and whether it was set.
+func extensionString(extensions map[string]interface{}, name string) (string, bool, error) {
+	var s string
+	ok, err := decodeExtension(extensions, name, &s)
+	return s, ok, err
+}
+
+// extensionBool returns the bool value of the OpenAPI extension with the provided name, and whether it was set.
+func extensionBool(extensions map[string]interface{}, name string) (bool, bool, error) {
+	var b bool
+	ok, err := decodeExtension(extensions, name, &b)
+	return b, ok, err
+}
+
+// decodeExtension decodes the value of the OpenAPI extension with the provided name into v. kin-openapi stores
+// extension values as raw JSON.
+func decodeExtension(extensions map[string]interface{}, name string, v interface{}) (bool, error) {
+	e, ok := extensions[name]
+	if !ok {
+		return false, nil
+	}
+
+	raw, ok := e.(json.RawMessage)
+	if !ok {
+		var err error
+		if raw, err = json.Marshal(e); err != nil {
+			return false, fmt.Errorf("json.Marshal: %s extension: %w", name, err)
+		}
+	}
+
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("json.Unmarshal: %s extension: %w", name, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,324 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type validateEndpointsTest struct {
+	doc         string            // input OpenAPI document
+	contentType string            // Content-Type of the test server's response
+	header      map[string]string // additional headers of the test server's response
+	body        string            // body of the test server's response
+	status      int               // status code of the test server's response
+	success     bool              // expected result of ValidateEndpoints
+}
+
+const validateEndpointsDocFmt = `openapi: 3.0.0
+info:
+  title: test
+  version: "1.0"
+paths:
+  /:
+    get:
+      responses:
+        "200":
+          description: OK
+%s
+`
+
+var validateEndpointsTests = []validateEndpointsTest{
+	// status code only
+	{
+		doc:         "",
+		contentType: "text/plain",
+		body:        "hello world",
+		status:      http.StatusOK,
+		success:     true,
+	},
+
+	// unexpected status code
+	{
+		doc:         "",
+		contentType: "text/plain",
+		body:        "hello world",
+		status:      http.StatusInternalServerError,
+		success:     false,
+	},
+
+	// body matches schema
+	{
+		doc: `          content:
+            application/json:
+              schema:
+                type: object
+                required: [message]
+                properties:
+                  message:
+                    type: string`,
+		contentType: "application/json",
+		body:        `{"message": "hello world"}`,
+		status:      http.StatusOK,
+		success:     true,
+	},
+
+	// body doesn't match schema
+	{
+		doc: `          content:
+            application/json:
+              schema:
+                type: object
+                required: [message]`,
+		contentType: "application/json",
+		body:        `{"error": "something went wrong"}`,
+		status:      http.StatusOK,
+		success:     false,
+	},
+
+	// body contains
+	{
+		doc:         `          x-sst-body-contains: hello`,
+		contentType: "text/plain",
+		body:        "hello world",
+		status:      http.StatusOK,
+		success:     true,
+	},
+
+	// body doesn't contain
+	{
+		doc:         `          x-sst-body-contains: hello`,
+		contentType: "text/html",
+		body:        "<h1>Error</h1>",
+		status:      http.StatusOK,
+		success:     false,
+	},
+
+	// body regex
+	{
+		doc:         `          x-sst-body-regex: "^hello \\w+$"`,
+		contentType: "text/plain",
+		body:        "hello world",
+		status:      http.StatusOK,
+		success:     true,
+	},
+
+	// body regex isn't a valid regular expression
+	{
+		doc:         `          x-sst-body-regex: "(hello"`,
+		contentType: "text/plain",
+		body:        "hello world",
+		status:      http.StatusOK,
+		success:     false,
+	},
+
+	// body equals JSON example
+	{
+		doc: `          x-sst-body-example: true
+          content:
+            application/json:
+              example:
+                message: hello world`,
+		contentType: "application/json",
+		body:        `{ "message": "hello world" }`,
+		status:      http.StatusOK,
+		success:     true,
+	},
+
+	// body doesn't equal JSON example
+	{
+		doc: `          x-sst-body-example: true
+          content:
+            application/json:
+              example:
+                message: hello world`,
+		contentType: "application/json",
+		body:        `{"message": "goodbye world"}`,
+		status:      http.StatusOK,
+		success:     false,
+	},
+
+	// body example required but none declared
+	{
+		doc:         `          x-sst-body-example: true`,
+		contentType: "text/plain",
+		body:        "hello world",
+		status:      http.StatusOK,
+		success:     false,
+	},
+
+	// declared header present
+	{
+		doc: `          headers:
+            X-Test-Header:
+              schema:
+                type: integer
+                maximum: 10`,
+		contentType: "text/plain",
+		header:      map[string]string{"X-Test-Header": "5"},
+		status:      http.StatusOK,
+		success:     true,
+	},
+
+	// declared header missing
+	{
+		doc: `          headers:
+            X-Test-Header:
+              schema:
+                type: string`,
+		contentType: "text/plain",
+		status:      http.StatusOK,
+		success:     false,
+	},
+
+	// declared header doesn't match schema
+	{
+		doc: `          headers:
+            X-Test-Header:
+              schema:
+                type: string
+                enum: [expected]`,
+		contentType: "text/plain",
+		header:      map[string]string{"X-Test-Header": "unexpected"},
+		status:      http.StatusOK,
+		success:     false,
+	},
+
+	// Content-Type with parameters matches declared media type
+	{
+		doc: `          content:
+            application/json: {}`,
+		contentType: "application/json; charset=utf-8",
+		body:        `{}`,
+		status:      http.StatusOK,
+		success:     true,
+	},
+
+	// Content-Type doesn't match declared media types
+	{
+		doc: `          content:
+            application/json: {}`,
+		contentType: "text/plain",
+		body:        `{}`,
+		status:      http.StatusOK,
+		success:     false,
+	},
+}
+
+func TestValidateEndpoints(t *testing.T) {
+	for i, tc := range validateEndpointsTests {
+		swagger, err := openapi3.NewSwaggerLoader().LoadSwaggerFromData([]byte(fmt.Sprintf(validateEndpointsDocFmt, tc.doc)))
+		if err != nil {
+			t.Errorf("#%d: openapi3.SwaggerLoader.LoadSwaggerFromData: %v", i, err)
+			continue
+		}
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", tc.contentType)
+			for k, v := range tc.header {
+				w.Header().Set(k, v)
+			}
+			w.WriteHeader(tc.status)
+			fmt.Fprint(w, tc.body)
+		}))
+
+		report, err := ValidateEndpoints(context.Background(), ts.URL, &swagger.Paths, "", 0)
+		ts.Close()
+
+		if err != nil {
+			t.Errorf("#%d: ValidateEndpoints: %v", i, err)
+			continue
+		}
+
+		if len(report.Results) != 1 {
+			t.Errorf("#%d: got %d results, want 1", i, len(report.Results))
+			continue
+		}
+
+		r := report.Results[0]
+		if r.Path != "/" || r.Method != http.MethodGet || r.StatusCode != tc.status || r.ResponseSnippet != tc.body {
+			t.Errorf("#%d: unexpected result: %#+v", i, r)
+		}
+
+		if success := report.Passed(); success != tc.success {
+			t.Errorf("#%d: result mismatch\nwant: %t\ngot: %t", i, tc.success, success)
+		}
+	}
+}
+
+func TestValidateEndpointsRequestFailed(t *testing.T) {
+	doc := `openapi: 3.0.0
+info:
+  title: test
+  version: "1.0"
+paths:
+  /:
+    get:
+      responses:
+        "200":
+          description: OK
+  /slow:
+    get:
+      responses:
+        "200":
+          description: OK
+`
+	swagger, err := openapi3.NewSwaggerLoader().LoadSwaggerFromData([]byte(doc))
+	if err != nil {
+		t.Fatalf("openapi3.SwaggerLoader.LoadSwaggerFromData: %v", err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}
+		fmt.Fprint(w, "hello world")
+	}))
+	defer ts.Close()
+
+	// The request that times out fails its test without preventing the other endpoints from being tested.
+	report, err := ValidateEndpoints(context.Background(), ts.URL, &swagger.Paths, "", 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("ValidateEndpoints: %v", err)
+	}
+
+	if len(report.Results) != 2 {
+		t.Fatalf("got %d results, want 2: %#+v", len(report.Results), report.Results)
+	}
+
+	if r := report.Results[0]; r.Path != "/" || !r.Passed() {
+		t.Errorf("got result %#+v, want / to pass", r)
+	}
+
+	if r := report.Results[1]; r.Path != "/slow" || !strings.HasPrefix(r.FailureReason, "request failed: ") {
+		t.Errorf("got result %#+v, want /slow to fail with a request error", r)
+	}
+}
+
+func TestValidateEndpointsCanceled(t *testing.T) {
+	swagger, err := openapi3.NewSwaggerLoader().LoadSwaggerFromData([]byte(fmt.Sprintf(validateEndpointsDocFmt, "")))
+	if err != nil {
+		t.Fatalf("openapi3.SwaggerLoader.LoadSwaggerFromData: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	// The in-flight request is canceled along with the context, rather than timing out.
+	if _, err := ValidateEndpoints(ctx, ts.URL, &swagger.Paths, "", time.Minute); !errors.Is(err, context.Canceled) {
+		t.Errorf("ValidateEndpoints: got error %v, want %v", err, context.Canceled)
+	}
+}
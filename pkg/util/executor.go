@@ -0,0 +1,201 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package util executes external commands, tests the endpoints of deployed samples, and reports the results.
+package util
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Executor executes external commands. All the external commands the tool runs, e.g. gcloud, git, and the commands
+// parsed from a sample's README, go through an Executor.
+type Executor interface {
+	// Run executes an exec.Cmd in the provided directory and returns a CommandResult describing its outcome. If the
+	// command fails, an error containing the command's combined stdout and stderr is returned along with the
+	// CommandResult. If the context is done before the command exits, the command is killed and the returned error
+	// wraps the context's error.
+	Run(ctx context.Context, cmd *exec.Cmd, dir string) (CommandResult, error)
+}
+
+// killWaitDelay is how long OSExecutor.Run waits for a killed command's output to be closed. It's left open past the
+// kill by the processes the command started that weren't killed along with it, e.g. on Windows.
+var killWaitDelay = 5 * time.Second
+
+// OSExecutor is an Executor that executes commands on the local machine through os/exec.
+type OSExecutor struct{}
+
+// Run implements Executor.
+func (OSExecutor) Run(ctx context.Context, cmd *exec.Cmd, dir string) (CommandResult, error) {
+	var stderr bytes.Buffer
+	var stdout bytes.Buffer
+	var stdcombined bytes.Buffer
+
+	// The output is written under mu, since the goroutines copying it may outlive Run if the command is killed.
+	var mu sync.Mutex
+
+	cmd.Dir = dir
+
+	cmd.Stdout = &lockedWriter{mu: &mu, w: io.MultiWriter(&stdout, &stdcombined)}
+	cmd.Stderr = &lockedWriter{mu: &mu, w: io.MultiWriter(&stderr, &stdcombined)}
+	setProcessGroup(cmd)
+
+	log.Printf("Executing %v\n", cmd)
+
+	// exitCode stays -1 unless the command is waited for, since cmd.ProcessState is only set once it is.
+	exitCode := -1
+
+	start := time.Now()
+	err := cmd.Start()
+	if err == nil {
+		done := make(chan error, 1)
+		go func() {
+			done <- cmd.Wait()
+		}()
+
+		select {
+		case err = <-done:
+			exitCode = cmd.ProcessState.ExitCode()
+		case <-ctx.Done():
+			log.Printf("Killing %v: %v\n", cmd, ctx.Err())
+			if kErr := killProcessGroup(cmd); kErr != nil {
+				log.Printf("Killing the process group of %v: %v\n", cmd, kErr)
+				cmd.Process.Kill()
+			}
+
+			select {
+			case err = <-done:
+				exitCode = cmd.ProcessState.ExitCode()
+			case <-time.After(killWaitDelay):
+				err = fmt.Errorf("output still open %v after the command was killed", killWaitDelay)
+			}
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	r := CommandResult{
+		Command:  cmd.String(),
+		Duration: time.Since(start),
+		ExitCode: exitCode,
+		Output:   strings.TrimSpace(string(stdcombined.Bytes())),
+		Stdout:   strings.TrimSpace(string(stdout.Bytes())),
+	}
+
+	if err != nil && ctx.Err() != nil {
+		return r, fmt.Errorf("exec.Cmd.Run: %v:\n%s\n%v: %w", cmd, r.Output, err, ctx.Err())
+	}
+
+	if err != nil {
+		return r, fmt.Errorf("exec.Cmd.Run: %v:\n%s\n%w", cmd, r.Output, err)
+	}
+
+	return r, nil
+}
+
+// lockedWriter is an io.Writer that holds a mutex while writing to the underlying io.Writer.
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+// Write implements io.Writer.
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.w.Write(p)
+}
+
+// FakeResponse is a scripted response of a FakeExecutor.
+type FakeResponse struct {
+	// Match is a substring of the command line (formatted by CommandLine) that a command must contain for this
+	// response to be used.
+	Match string
+
+	// Stdout is returned as the command's stdout and combined output.
+	Stdout string
+
+	// ExitCode is the command's exit code. A non-zero exit code makes the command fail.
+	ExitCode int
+}
+
+// FakeExecutor is an Executor that doesn't execute any commands. Instead, it records every command it's asked to run
+// and answers with the first of its Responses that matches. Commands that don't match any response succeed with no
+// output, and commands run with a done context fail. It's safe for concurrent use.
+type FakeExecutor struct {
+	Responses []FakeResponse
+
+	mu       sync.Mutex
+	commands []string
+	dirs     []string
+}
+
+// Run implements Executor.
+func (e *FakeExecutor) Run(ctx context.Context, cmd *exec.Cmd, dir string) (CommandResult, error) {
+	c := CommandLine(cmd)
+
+	e.mu.Lock()
+	e.commands = append(e.commands, c)
+	e.dirs = append(e.dirs, dir)
+	e.mu.Unlock()
+
+	r := CommandResult{Command: c}
+	if err := ctx.Err(); err != nil {
+		r.ExitCode = -1
+		return r, fmt.Errorf("exec.Cmd.Run: %s: %w", c, err)
+	}
+
+	for _, resp := range e.Responses {
+		if !strings.Contains(c, resp.Match) {
+			continue
+		}
+
+		r.Stdout = resp.Stdout
+		r.Output = resp.Stdout
+		r.ExitCode = resp.ExitCode
+		break
+	}
+
+	if r.ExitCode != 0 {
+		return r, fmt.Errorf("exec.Cmd.Run: %s:\n%s\nexit status %d", c, r.Output, r.ExitCode)
+	}
+
+	return r, nil
+}
+
+// Commands returns the command lines of all the commands the FakeExecutor was asked to run, in order.
+func (e *FakeExecutor) Commands() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]string(nil), e.commands...)
+}
+
+// Dirs returns the directories of all the commands the FakeExecutor was asked to run, in order.
+func (e *FakeExecutor) Dirs() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]string(nil), e.dirs...)
+}
@@ -0,0 +1,56 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestOSExecutorRun(t *testing.T) {
+	r, err := OSExecutor{}.Run(context.Background(), exec.Command("echo", "hello", "world"), "")
+	if err != nil {
+		t.Fatalf("OSExecutor.Run: %v", err)
+	}
+
+	if r.Stdout != "hello world" || r.ExitCode != 0 {
+		t.Errorf("result mismatch\nwant stdout: hello world, exit code: 0\ngot: %#+v", r)
+	}
+}
+
+func TestOSExecutorRunCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := OSExecutor{}.Run(ctx, exec.Command("sleep", "10"), "")
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error mismatch\nwant: %v\ngot: %v", context.DeadlineExceeded, err)
+	}
+
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("command wasn't killed when the context was done: ran for %v", d)
+	}
+}
+
+func TestOSExecutorRunCancelChildren(t *testing.T) {
+	defer func(d time.Duration) { killWaitDelay = d }(killWaitDelay)
+	killWaitDelay = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// The shell's sleep child keeps the command's output open unless it's killed along with the shell.
+	start := time.Now()
+	_, err := OSExecutor{}.Run(ctx, exec.Command("sh", "-c", "sleep 10; echo done"), "")
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error mismatch\nwant: %v\ngot: %v", context.DeadlineExceeded, err)
+	}
+
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("command's children weren't killed when the context was done: ran for %v", d)
+	}
+}
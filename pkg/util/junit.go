@@ -0,0 +1,163 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// junitTestSuites is the root element of a JUnit XML report.
+type junitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite is a group of JUnit testcases.
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is a single JUnit testcase. Failure is nil if the testcase passed.
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// junitFailure describes why a JUnit testcase failed.
+type junitFailure struct {
+	Message  string `xml:"message,attr"`
+	Contents string `xml:",chardata"`
+}
+
+// JUnitSuite is a named group of lifecycle command results, endpoint test results, and other outcomes that will be
+// written as a single JUnit testsuite.
+type JUnitSuite struct {
+	Name     string
+	Commands []CommandResult
+	Tests    *TestReport
+	Cases    []JUnitCase
+}
+
+// JUnitCase is an outcome that isn't a command or an endpoint test, e.g. the outcome of the whole run or of a resource
+// deletion. Failure is empty if it passed.
+type JUnitCase struct {
+	Name    string
+	Failure string
+}
+
+// WriteJUnitReport writes the provided suites to the provided io.Writer as a JUnit XML report. Every command, every
+// endpoint test, and every JUnitCase becomes a testcase. Failed commands include their combined stdout and stderr in
+// the failure message.
+func WriteJUnitReport(w io.Writer, className string, suites []JUnitSuite) error {
+	var root junitTestSuites
+	for _, s := range suites {
+		ts := junitTestSuite{Name: s.Name}
+
+		var total time.Duration
+		for _, c := range s.Commands {
+			tc := junitTestCase{
+				Name:      c.Command,
+				ClassName: className,
+				Time:      junitTime(c.Duration),
+			}
+
+			if c.ExitCode != 0 {
+				tc.Failure = &junitFailure{
+					Message:  fmt.Sprintf("exit status %d", c.ExitCode),
+					Contents: c.Output,
+				}
+				ts.Failures++
+			} else {
+				tc.SystemOut = c.Output
+			}
+
+			total += c.Duration
+			ts.TestCases = append(ts.TestCases, tc)
+		}
+
+		if s.Tests != nil {
+			for _, r := range s.Tests.Results {
+				tc := junitTestCase{
+					Name:      r.Name(),
+					ClassName: className,
+					Time:      junitTime(r.Latency),
+				}
+
+				if !r.Passed() {
+					tc.Failure = &junitFailure{
+						Message: r.FailureReason,
+						Contents: fmt.Sprintf("Expected status codes: %s\nGot status code: %d\nResponse body:\n%s",
+							strings.Join(r.ExpectedStatuses, ", "), r.StatusCode, r.ResponseSnippet),
+					}
+					ts.Failures++
+				}
+
+				total += r.Latency
+				ts.TestCases = append(ts.TestCases, tc)
+			}
+		}
+
+		for _, c := range s.Cases {
+			tc := junitTestCase{
+				Name:      c.Name,
+				ClassName: className,
+				Time:      junitTime(0),
+			}
+
+			if c.Failure != "" {
+				tc.Failure = &junitFailure{
+					Message:  strings.SplitN(c.Failure, "\n", 2)[0],
+					Contents: c.Failure,
+				}
+				ts.Failures++
+			}
+
+			ts.TestCases = append(ts.TestCases, tc)
+		}
+
+		ts.Tests = len(ts.TestCases)
+		ts.Time = junitTime(total)
+		root.TestSuites = append(root.TestSuites, ts)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(root); err != nil {
+		return fmt.Errorf("xml.Encoder.Encode: %w", err)
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// junitTime formats a time.Duration as the number of seconds JUnit reports expect.
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,59 @@
+package util
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteJUnitReport(t *testing.T) {
+	suites := []JUnitSuite{
+		{
+			Name: "build and deploy",
+			Commands: []CommandResult{
+				{Command: "gcloud builds submit", Duration: time.Second, Output: "build ok"},
+				{Command: "gcloud run deploy", Duration: 2 * time.Second, ExitCode: 1, Output: "ERROR: permission denied"},
+			},
+		},
+		{
+			Name: "endpoints",
+			Tests: &TestReport{
+				Results: []TestResult{
+					{Path: "/", Method: "GET", StatusCode: 500, ExpectedStatuses: []string{"200"}, FailureReason: "unexpected status code 500"},
+				},
+			},
+		},
+		{
+			Name: "run",
+			Cases: []JUnitCase{
+				{Name: "sst/run", Failure: "invalid config file\nunknown key sampel"},
+				{Name: "sst/cleanup Cloud Run service hello", Failure: "permission denied"},
+				{Name: "sst/cleanup container image gcr.io/project/hello"},
+			},
+		},
+	}
+
+	var b bytes.Buffer
+	if err := WriteJUnitReport(&b, "sample", suites); err != nil {
+		t.Fatalf("WriteJUnitReport: %v", err)
+	}
+	out := b.String()
+
+	want := []string{
+		`<testsuite name="build and deploy" tests="2" failures="1" time="3.000">`,
+		`<testcase name="gcloud builds submit" classname="sample" time="1.000">`,
+		`<failure message="exit status 1">ERROR: permission denied</failure>`,
+		`<testsuite name="endpoints" tests="1" failures="1" time="0.000">`,
+		`<failure message="unexpected status code 500">`,
+		`<testsuite name="run" tests="3" failures="2" time="0.000">`,
+		`<failure message="invalid config file">invalid config file&#xA;unknown key sampel</failure>`,
+		`<failure message="permission denied">permission denied</failure>`,
+		`<testcase name="sst/cleanup container image gcr.io/project/hello" classname="sample" time="0.000"></testcase>`,
+	}
+	for _, w := range want {
+		if !strings.Contains(out, w) {
+			t.Errorf("report doesn't contain %s\ngot:\n%s", w, out)
+		}
+	}
+}
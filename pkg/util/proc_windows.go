@@ -0,0 +1,28 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on Windows, where process groups aren't supported.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the provided started command. On Windows, the processes it started aren't killed along with
+// it, so Run stops waiting for them after killWaitDelay instead.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
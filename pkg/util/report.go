@@ -0,0 +1,127 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxResponseSnippetLen is the maximum number of bytes of a response body that are kept in a TestResult.
+const maxResponseSnippetLen = 1024
+
+// TestResult holds the outcome of a single endpoint test request.
+type TestResult struct {
+	// The endpoint path that was requested, as declared in the OpenAPI document.
+	Path string `json:"path"`
+
+	// The HTTP method of the request.
+	Method string `json:"method"`
+
+	// The MIME type of the request body, if any.
+	MimeType string `json:"mimeType,omitempty"`
+
+	// The request body that was sent.
+	RequestBody string `json:"requestBody,omitempty"`
+
+	// The status code of the response.
+	StatusCode int `json:"statusCode"`
+
+	// The status codes declared as expected responses of the operation.
+	ExpectedStatuses []string `json:"expectedStatuses"`
+
+	// How long it took to receive the response.
+	Latency time.Duration `json:"latency"`
+
+	// The beginning of the response body, truncated to maxResponseSnippetLen bytes.
+	ResponseSnippet string `json:"responseSnippet,omitempty"`
+
+	// Why the test failed. Empty if the test passed.
+	FailureReason string `json:"failureReason,omitempty"`
+}
+
+// Passed reports whether the test passed.
+func (r TestResult) Passed() bool {
+	return r.FailureReason == ""
+}
+
+// Name returns a short human-readable name for the test, e.g. "POST /submit (application/json)".
+func (r TestResult) Name() string {
+	n := r.Method + " " + r.Path
+	if r.MimeType != "" {
+		n += " (" + r.MimeType + ")"
+	}
+
+	return n
+}
+
+// TestReport holds the results of all the endpoint test requests made against a service.
+type TestReport struct {
+	Results []TestResult `json:"results"`
+}
+
+// Passed reports whether all the tests in the report passed.
+func (r *TestReport) Passed() bool {
+	return len(r.Failed()) == 0
+}
+
+// Failed returns the results of the tests that failed.
+func (r *TestReport) Failed() []TestResult {
+	var f []TestResult
+	for _, res := range r.Results {
+		if !res.Passed() {
+			f = append(f, res)
+		}
+	}
+
+	return f
+}
+
+// WriteSummary writes a human-readable summary of the report to the provided io.Writer. Failed tests are listed with
+// their failure reason and response snippet.
+func (r *TestReport) WriteSummary(w io.Writer) error {
+	for _, res := range r.Results {
+		status := "PASS"
+		if !res.Passed() {
+			status = "FAIL"
+		}
+
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%d\t%v\n", status, res.Name(), res.StatusCode, res.Latency); err != nil {
+			return err
+		}
+	}
+
+	for _, res := range r.Failed() {
+		_, err := fmt.Fprintf(w, "\n--- FAIL: %s\nExpected status codes: %s\nGot status code: %d\nReason: %s\nResponse body:\n%s\n",
+			res.Name(), strings.Join(res.ExpectedStatuses, ", "), res.StatusCode, res.FailureReason, res.ResponseSnippet)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "\n%d passed, %d failed\n", len(r.Results)-len(r.Failed()), len(r.Failed()))
+	return err
+}
+
+// responseSnippet truncates a response body to maxResponseSnippetLen bytes.
+func responseSnippet(body []byte) string {
+	if len(body) <= maxResponseSnippetLen {
+		return string(body)
+	}
+
+	return string(body[:maxResponseSnippetLen]) + "..."
+}